@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,6 +20,8 @@ const (
 
 var apiKey string
 
+var apiClient *ApiClient
+
 type ApiClient struct {
 	client *http.Client
 	apiKey string
@@ -86,18 +90,73 @@ func (c *ApiClient) makeRequest(method, endpoint string, body []byte) ([]byte, e
 	return responseBody, nil
 }
 
-func (c *ApiClient) SearchMods(searchFilter string, page int) (SearchModsResponse, error) {
+// BrowseFilters is everything the search endpoint filters and sorts on
+// besides the search text. Zero IDs and empty strings mean any.
+type BrowseFilters struct {
+	ClassID     int    `json:"class_id"`
+	CategoryID  int    `json:"category_id"`
+	GameVersion string `json:"game_version"`
+	SortField   int    `json:"sort_field"`
+	SortOrder   string `json:"sort_order"`
+	// an author ID or a name, names are looked up to get the ID
+	Author   string `json:"author"`
+	PageSize int    `json:"page_size"`
+}
+
+// the API caps pageSize at 50
+var browsePageSizes = []int{10, 20, 30, 50}
+
+func defaultBrowseFilters() BrowseFilters {
+	return BrowseFilters{SortField: SortFieldPopularity, SortOrder: SortOrderDescending, PageSize: 20}
+}
+
+// IsDefault is true when the filters don't narrow the search down, with no
+// search text either the Browse tab shows the featured mods instead.
+func (f BrowseFilters) IsDefault() bool {
+	return f == defaultBrowseFilters()
+}
+
+func (c *ApiClient) SearchMods(searchFilter string, filters BrowseFilters, page int) (SearchModsResponse, error) {
 	var result SearchModsResponse
 	
+	pageSize := filters.PageSize
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 20
+	}
+	sortField := filters.SortField
+	if sortField == 0 {
+		sortField = SortFieldPopularity
+	}
+	sortOrder := filters.SortOrder
+	if sortOrder != SortOrderAscending {
+		sortOrder = SortOrderDescending
+	}
+	
 	params := url.Values{}
 	params.Add("gameId", strconv.Itoa(sims4GameID))
 	if searchFilter != "" {
 		params.Add("searchFilter", searchFilter)
 	}
-	params.Add("pageSize", "20")
-	params.Add("index", strconv.Itoa((page-1)*20))
-	params.Add("sortField", "2") // 2 = Popularity, this API is so damn unintuitive
-	params.Add("sortOrder", "desc")
+	if filters.ClassID > 0 {
+		params.Add("classId", strconv.Itoa(filters.ClassID))
+	}
+	if filters.CategoryID > 0 {
+		params.Add("categoryId", strconv.Itoa(filters.CategoryID))
+	}
+	if filters.GameVersion != "" {
+		params.Add("gameVersion", filters.GameVersion)
+	}
+	if filters.Author != "" {
+		id, err := c.ResolveAuthor(filters.Author)
+		if err != nil {
+			return result, err
+		}
+		params.Add("authorId", strconv.Itoa(id))
+	}
+	params.Add("pageSize", strconv.Itoa(pageSize))
+	params.Add("index", strconv.Itoa((page-1)*pageSize))
+	params.Add("sortField", strconv.Itoa(sortField))
+	params.Add("sortOrder", sortOrder)
 	
 	endpoint := "/v1/mods/search?" + params.Encode()
 	
@@ -110,6 +169,73 @@ func (c *ApiClient) SearchMods(searchFilter string, page int) (SearchModsRespons
 	return result, err
 }
 
+// author names already looked up, they don't change
+var (
+	authorIDsMu sync.Mutex
+	authorIDs   = make(map[string]int)
+)
+
+// ResolveAuthor turns an author name into the ID search filters on. Numbers
+// are taken as IDs already. The search text also matches author names, so the
+// author is picked out of the mods a search for the name finds, an exact name
+// beats one that only contains it.
+func (c *ApiClient) ResolveAuthor(author string) (int, error) {
+	author = strings.TrimSpace(author)
+	if id, err := strconv.Atoi(author); err == nil && id > 0 {
+		return id, nil
+	}
+	name := strings.ToLower(author)
+
+	authorIDsMu.Lock()
+	id, ok := authorIDs[name]
+	authorIDsMu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	params := url.Values{}
+	params.Add("gameId", strconv.Itoa(sims4GameID))
+	params.Add("searchFilter", author)
+	params.Add("pageSize", "50")
+	params.Add("sortField", strconv.Itoa(SortFieldPopularity))
+	params.Add("sortOrder", SortOrderDescending)
+
+	responseBody, err := c.makeRequest("GET", "/v1/mods/search?"+params.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	var result SearchModsResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return 0, err
+	}
+
+	partial := 0
+	for _, mod := range result.Data {
+		for _, modAuthor := range mod.Authors {
+			lower := strings.ToLower(modAuthor.Name)
+			if lower == name {
+				id = modAuthor.ID
+			} else if partial == 0 && strings.Contains(lower, name) {
+				partial = modAuthor.ID
+			}
+		}
+		if id != 0 {
+			break
+		}
+	}
+	if id == 0 {
+		id = partial
+	}
+	if id == 0 {
+		return 0, fmt.Errorf("no author called %q found", author)
+	}
+
+	authorIDsMu.Lock()
+	authorIDs[name] = id
+	authorIDsMu.Unlock()
+	return id, nil
+}
+
 func (c *ApiClient) GetMod(modId int) (GetModResponse, error) {
 	var result GetModResponse
 	
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
@@ -8,6 +11,9 @@ import (
 )
 
 func setupApp() fyne.App {
+	// before the tabs scan the Mods folder
+	applyQueuedChanges()
+	
 	a := app.New()
 	a.Settings().SetTheme(newDarkTheme())
 	
@@ -17,12 +23,26 @@ func setupApp() fyne.App {
 	tabs := container.NewAppTabs(
 		container.NewTabItem("Mods", setupModsTab()),
 		container.NewTabItem("Browse", setupBrowserTab()),
+		container.NewTabItem("Diagnostics", setupDiagnosticsTab()),
 		container.NewTabItem("Settings", setupSettingsTab()),
 	)
 	
 	tabs.SetTabLocation(container.TabLocationTop)
 	
 	mainWindow.SetContent(tabs)
+	
+	onGameStateChange(func(running bool) {
+		if running {
+			if watcher := currentExceptionWatcher(); watcher != nil {
+				watcher.NewSession()
+			}
+			return
+		}
+		if pending := pendingOpsCount(); pending > 0 {
+			a.SendNotification(fyne.NewNotification("Sims 4 Mod Manager", fmt.Sprintf("%d queued changes couldn't be applied", pending)))
+		}
+	})
+	go monitorGameProcess(5 * time.Second)
 	mainWindow.ShowAndRun()
 	
 	return a
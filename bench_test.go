@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// go test -bench Scan -run ^$ times every scan stage over a synthetic Mods
+// folder, with one worker and with the pool.
+
+const (
+	benchFiles          = 2000
+	benchFilesPerFolder = 50
+)
+
+// writeSyntheticPackage writes a package that looks enough like CC to go
+// through the whole pipeline: a CAS part, its names and some incompressible
+// filler standing in for the textures.
+func writeSyntheticPackage(path string, rng *rand.Rand) error {
+	var casp bytes.Buffer
+	w := func(v any) { binary.Write(&casp, binary.LittleEndian, v) }
+	w(uint32(46)) // version
+	w(uint64(0))  // tgi offset, presets
+	casp.WriteByte(0)
+	w(float32(1))
+	w(uint16(0))
+	w(uint32(0))
+	w(uint32(0))
+	casp.WriteByte(0)
+	casp.WriteByte(0)
+	w(uint64(0))
+	w(uint64(0))
+	w(uint64(0))
+	w(uint32(0)) // tags
+	w(uint32(0)) // price
+	titleKey, descriptionKey := rng.Uint32(), rng.Uint32()
+	w(titleKey)
+	w(descriptionKey)
+	w(uint32(0))
+	casp.WriteByte(0)
+	w(int32(2 + rng.Intn(40)))
+
+	var stbl bytes.Buffer
+	title, description := fmt.Sprintf("Synthetic %08X", titleKey), "Made up for benchmarking"
+	stbl.WriteString("STBL")
+	binary.Write(&stbl, binary.LittleEndian, uint16(5))
+	stbl.WriteByte(0)
+	binary.Write(&stbl, binary.LittleEndian, uint64(2))
+	binary.Write(&stbl, binary.LittleEndian, uint16(0))
+	binary.Write(&stbl, binary.LittleEndian, uint32(len(title)+len(description)+2))
+	for _, s := range []struct {
+		key  uint32
+		text string
+	}{{titleKey, title}, {descriptionKey, description}} {
+		binary.Write(&stbl, binary.LittleEndian, s.key)
+		stbl.WriteByte(0)
+		binary.Write(&stbl, binary.LittleEndian, uint16(len(s.text)))
+		stbl.WriteString(s.text)
+	}
+
+	filler := make([]byte, 8<<10+rng.Intn(24<<10))
+	rng.Read(filler)
+
+	instance := rng.Uint64()
+	resources := []struct {
+		key  ResourceKey
+		data []byte
+	}{
+		{ResourceKey{Type: resourceCASPart, Instance: instance}, casp.Bytes()},
+		{ResourceKey{Type: resourceStringTable, Instance: instance >> 8}, stbl.Bytes()},
+		{ResourceKey{Type: 0x00B2D882, Instance: instance ^ 1}, filler},
+	}
+
+	var body, index bytes.Buffer
+	binary.Write(&index, binary.LittleEndian, uint32(0)) // no shared fields
+	for _, res := range resources {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(res.data)
+		zw.Close()
+
+		for _, v := range []uint32{
+			res.key.Type, res.key.Group, uint32(res.key.Instance >> 32), uint32(res.key.Instance),
+			uint32(dbpfHeaderSize + body.Len()), uint32(compressed.Len()) | 0x80000000, uint32(len(res.data)),
+			compressionZlib | 1<<16,
+		} {
+			binary.Write(&index, binary.LittleEndian, v)
+		}
+		body.Write(compressed.Bytes())
+	}
+
+	header := make([]byte, dbpfHeaderSize)
+	copy(header, "DBPF")
+	binary.LittleEndian.PutUint32(header[4:], 2)
+	binary.LittleEndian.PutUint32(header[8:], 1)
+	binary.LittleEndian.PutUint32(header[36:], uint32(len(resources)))
+	binary.LittleEndian.PutUint32(header[44:], uint32(index.Len()))
+	binary.LittleEndian.PutUint32(header[64:], uint32(dbpfHeaderSize+body.Len()))
+
+	data := append(append(header, body.Bytes()...), index.Bytes()...)
+	return os.WriteFile(path, data, 0644)
+}
+
+// benchTree writes the synthetic Mods folder and moves into a temp dir of its
+// own so the index and recent mods files don't touch the real ones.
+func benchTree(b *testing.B) string {
+	b.Helper()
+	root := b.TempDir()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { os.Chdir(cwd) })
+
+	modsDir := filepath.Join(root, "Mods")
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < benchFiles; i++ {
+		dir := filepath.Join(modsDir, fmt.Sprintf("creator%02d", i/benchFilesPerFolder%20), fmt.Sprintf("set%03d", i/benchFilesPerFolder))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := writeSyntheticPackage(filepath.Join(dir, fmt.Sprintf("cc_%05d.package", i)), rng); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	saved := modIndex
+	b.Cleanup(func() { modIndex = saved })
+	return modsDir
+}
+
+func resetModIndex(b *testing.B) {
+	modIndex = nil
+	if err := os.Remove(modIndexFile); err != nil && !os.IsNotExist(err) {
+		b.Fatal(err)
+	}
+}
+
+// forEachWorkers runs bench with a single worker and with the default pool.
+func forEachWorkers(b *testing.B, bench func(b *testing.B)) {
+	saved := scanWorkers
+	defer func() { scanWorkers = saved }()
+	for _, workers := range []int{1, saved} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			scanWorkers = workers
+			bench(b)
+		})
+	}
+}
+
+func BenchmarkScanWalk(b *testing.B) {
+	modsDir := benchTree(b)
+	forEachWorkers(b, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := walkModFiles(context.Background(), modsDir, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkScanIndexCold(b *testing.B) {
+	modsDir := benchTree(b)
+	mods, err := walkModFiles(context.Background(), modsDir, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	forEachWorkers(b, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			resetModIndex(b)
+			b.StartTimer()
+			if _, err := UpdateModIndexContext(context.Background(), modsDir, mods, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkScanIndexUnchanged(b *testing.B) {
+	modsDir := benchTree(b)
+	mods, err := walkModFiles(context.Background(), modsDir, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	resetModIndex(b)
+	if _, err := UpdateModIndexContext(context.Background(), modsDir, mods, nil); err != nil {
+		b.Fatal(err)
+	}
+	forEachWorkers(b, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := UpdateModIndexContext(context.Background(), modsDir, mods, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkScanHashCold(b *testing.B) {
+	modsDir := benchTree(b)
+	forEachWorkers(b, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			resetModIndex(b)
+			b.StartTimer()
+			if _, err := IndexedFingerprintsContext(context.Background(), modsDir, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkScanHashCached(b *testing.B) {
+	modsDir := benchTree(b)
+	resetModIndex(b)
+	if _, err := IndexedFingerprintsContext(context.Background(), modsDir, nil); err != nil {
+		b.Fatal(err)
+	}
+	forEachWorkers(b, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := IndexedFingerprintsContext(context.Background(), modsDir, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -16,9 +18,10 @@ import (
 	"fyne.io/fyne/v2/widget"
 )
 
-var apiClient *ApiClient
 var currentPage = 1
 var lastSearch = ""
+var ownedPacksOnly = false
+var browseFilters = defaultBrowseFilters()
 
 func setupBrowserTab() fyne.CanvasObject {
 	if apiClient == nil {
@@ -36,11 +39,21 @@ func setupBrowserTab() fyne.CanvasObject {
 		}
 	}
 
+	if settings, err := LoadSettings(); err == nil {
+		browseFilters = settings.BrowseFilters
+	}
+	
 	searchEntry := widget.NewEntry()
 	searchEntry.SetPlaceHolder("Search mods...")
 	
 	contentContainer := container.NewVBox()
 	
+	filtersRow := setupBrowseFilters(func() {
+		currentPage = 1
+		lastSearch = searchEntry.Text
+		refreshModBrowser(lastSearch, currentPage, contentContainer)
+	})
+	
 	searchButton := widget.NewButton("Search", func() {
 		currentPage = 1
 		lastSearch = searchEntry.Text
@@ -66,12 +79,19 @@ func setupBrowserTab() fyne.CanvasObject {
 		nextButton,
 	)
 	
-	searchRow := container.NewBorder(nil, nil, nil, searchButton, searchEntry)
+	ownedCheck := widget.NewCheck("Only mods for packs I own", func(checked bool) {
+		ownedPacksOnly = checked
+		refreshModBrowser(lastSearch, currentPage, contentContainer)
+	})
+	ownedCheck.SetChecked(ownedPacksOnly)
+	
+	searchRow := container.NewBorder(nil, nil, nil, container.NewHBox(ownedCheck, searchButton), searchEntry)
 	
-	loadFeaturedMods(contentContainer)
+	// featured mods unless the saved filters ask for a search
+	refreshModBrowser(lastSearch, currentPage, contentContainer)
 	
 	return container.NewBorder(
-		container.NewVBox(widget.NewLabel("Browse Mods"), searchRow),
+		container.NewVBox(widget.NewLabel("Browse Mods"), searchRow, filtersRow),
 		pageControls,
 		nil, nil,
 		container.NewVScroll(contentContainer),
@@ -155,19 +175,19 @@ func loadFeaturedMods(container *fyne.Container) {
 		container.RemoveAll()
 		
 		container.Add(widget.NewLabel("Popular Mods"))
-		for _, mod := range featured.Data.Popular {
+		for _, mod := range filterOwnedPacks(featured.Data.Popular) {
 			container.Add(createModCard(mod))
 		}
 		
 		container.Add(widget.NewSeparator())
 		container.Add(widget.NewLabel("Featured Mods"))
-		for _, mod := range featured.Data.Featured {
+		for _, mod := range filterOwnedPacks(featured.Data.Featured) {
 			container.Add(createModCard(mod))
 		}
 		
 		container.Add(widget.NewSeparator())
 		container.Add(widget.NewLabel("Recently Updated Mods"))
-		for _, mod := range featured.Data.RecentlyUpdated {
+		for _, mod := range filterOwnedPacks(featured.Data.RecentlyUpdated) {
 			container.Add(createModCard(mod))
 		}
 		
@@ -185,11 +205,11 @@ func refreshModBrowser(search string, page int, container *fyne.Container) {
 		var searchResults SearchModsResponse
 		var err error
 		
-		if search == "" {
+		if search == "" && browseFilters.IsDefault() {
 			loadFeaturedMods(container)
 			return
 		} else {
-			searchResults, err = apiClient.SearchMods(search, page)
+			searchResults, err = apiClient.SearchMods(search, browseFilters, page)
 			if err != nil {
 				container.RemoveAll()
 				container.Add(widget.NewLabel("Error searching mods: " + err.Error()))
@@ -206,10 +226,12 @@ func refreshModBrowser(search string, page int, container *fyne.Container) {
 			return
 		}
 		
-		resultLabel := widget.NewLabel(fmt.Sprintf("Showing %d of %d results", len(searchResults.Data), searchResults.Pagination.TotalCount))
+		mods := filterOwnedPacks(searchResults.Data)
+		
+		resultLabel := widget.NewLabel(fmt.Sprintf("Showing %d of %d results", len(mods), searchResults.Pagination.TotalCount))
 		container.Add(resultLabel)
 		
-		for _, mod := range searchResults.Data {
+		for _, mod := range mods {
 			container.Add(createModCard(mod))
 		}
 		
@@ -217,6 +239,321 @@ func refreshModBrowser(search string, page int, container *fyne.Container) {
 	}()
 }
 
+const (
+	anyClass    = "Any class"
+	anyCategory = "Any category"
+	anyVersion  = "Any game version"
+)
+
+var browseSortFields = []struct {
+	field int
+	name  string
+}{
+	{SortFieldFeatured, "Featured"},
+	{SortFieldPopularity, "Popularity"},
+	{SortFieldLastUpdated, "Last Updated"},
+	{SortFieldName, "Name"},
+	{SortFieldAuthor, "Author"},
+	{SortFieldTotalDownloads, "Total Downloads"},
+	{SortFieldCategory, "Category"},
+	{SortFieldGameVersion, "Game Version"},
+	{SortFieldDailyDownloads, "Daily Downloads"},
+	{SortFieldRating, "Rating"},
+	{SortFieldCreatedAt, "Created"},
+	{SortFieldReleaseDate, "Release Date"},
+}
+
+var browseSortOrders = []struct {
+	order string
+	name  string
+}{
+	{SortOrderDescending, "Descending"},
+	{SortOrderAscending, "Ascending"},
+}
+
+// setupBrowseFilters builds the filter controls for browseFilters. Every
+// change is saved and runs onChange. Classes, categories and game versions
+// come from the API, the pickers fill in once they've loaded.
+func setupBrowseFilters(onChange func()) fyne.CanvasObject {
+	var (
+		categories  []Category
+		classIDs    = map[string]int{anyClass: 0}
+		categoryIDs = map[string]int{anyCategory: 0}
+		// setting the pickers from code shouldn't count as a change
+		updating bool
+	)
+	
+	changed := func() {
+		saveBrowseFilters()
+		onChange()
+	}
+	
+	classSelect := widget.NewSelect([]string{anyClass}, nil)
+	categorySelect := widget.NewSelect([]string{anyCategory}, nil)
+	versionSelect := widget.NewSelect([]string{anyVersion}, nil)
+	
+	showCategories := func() {
+		var options []string
+		categoryIDs = map[string]int{anyCategory: 0}
+		counts := make(map[string]int)
+		var shown []Category
+		for _, category := range categories {
+			if category.IsClass || (browseFilters.ClassID != 0 && category.ClassID != browseFilters.ClassID) {
+				continue
+			}
+			shown = append(shown, category)
+			counts[category.Name]++
+		}
+		
+		selected := anyCategory
+		for _, category := range shown {
+			label := category.Name
+			// the same name can be under several classes
+			if counts[category.Name] > 1 {
+				for name, id := range classIDs {
+					if id == category.ClassID && id != 0 {
+						label += " (" + name + ")"
+					}
+				}
+			}
+			options = append(options, label)
+			categoryIDs[label] = category.ID
+			if category.ID == browseFilters.CategoryID {
+				selected = label
+			}
+		}
+		sort.Strings(options)
+		
+		updating = true
+		categorySelect.Options = append([]string{anyCategory}, options...)
+		categorySelect.SetSelected(selected)
+		updating = false
+		
+		// the saved category may belong to another class
+		if selected == anyCategory && browseFilters.CategoryID != 0 && len(categories) > 0 {
+			browseFilters.CategoryID = 0
+		}
+	}
+	
+	classSelect.OnChanged = func(name string) {
+		if updating {
+			return
+		}
+		browseFilters.ClassID = classIDs[name]
+		showCategories()
+		changed()
+	}
+	categorySelect.OnChanged = func(name string) {
+		if updating {
+			return
+		}
+		browseFilters.CategoryID = categoryIDs[name]
+		changed()
+	}
+	versionSelect.OnChanged = func(version string) {
+		if updating {
+			return
+		}
+		if version == anyVersion {
+			version = ""
+		}
+		browseFilters.GameVersion = version
+		changed()
+	}
+	
+	var sortNames []string
+	for _, sortField := range browseSortFields {
+		sortNames = append(sortNames, "Sort by "+sortField.name)
+	}
+	sortSelect := widget.NewSelect(sortNames, func(name string) {
+		if updating {
+			return
+		}
+		for _, sortField := range browseSortFields {
+			if "Sort by "+sortField.name == name {
+				browseFilters.SortField = sortField.field
+			}
+		}
+		changed()
+	})
+	
+	var orderNames []string
+	for _, sortOrder := range browseSortOrders {
+		orderNames = append(orderNames, sortOrder.name)
+	}
+	orderSelect := widget.NewSelect(orderNames, func(name string) {
+		if updating {
+			return
+		}
+		for _, sortOrder := range browseSortOrders {
+			if sortOrder.name == name {
+				browseFilters.SortOrder = sortOrder.order
+			}
+		}
+		changed()
+	})
+	
+	var pageSizeNames []string
+	for _, size := range browsePageSizes {
+		pageSizeNames = append(pageSizeNames, fmt.Sprintf("%d per page", size))
+	}
+	pageSizeSelect := widget.NewSelect(pageSizeNames, func(name string) {
+		if updating {
+			return
+		}
+		fmt.Sscanf(name, "%d", &browseFilters.PageSize)
+		changed()
+	})
+	
+	authorEntry := widget.NewEntry()
+	authorEntry.SetPlaceHolder("Author name or ID")
+	authorEntry.OnSubmitted = func(author string) {
+		browseFilters.Author = strings.TrimSpace(author)
+		changed()
+	}
+	
+	// showFilters puts browseFilters into the pickers
+	showFilters := func() {
+		updating = true
+		
+		className := anyClass
+		for name, id := range classIDs {
+			if id == browseFilters.ClassID && id != 0 {
+				className = name
+			}
+		}
+		classSelect.SetSelected(className)
+		if browseFilters.GameVersion == "" {
+			versionSelect.SetSelected(anyVersion)
+		} else {
+			versionSelect.SetSelected(browseFilters.GameVersion)
+		}
+		for _, sortField := range browseSortFields {
+			if sortField.field == browseFilters.SortField {
+				sortSelect.SetSelected("Sort by " + sortField.name)
+			}
+		}
+		for _, sortOrder := range browseSortOrders {
+			if sortOrder.order == browseFilters.SortOrder {
+				orderSelect.SetSelected(sortOrder.name)
+			}
+		}
+		pageSizeSelect.SetSelected(fmt.Sprintf("%d per page", browseFilters.PageSize))
+		authorEntry.SetText(browseFilters.Author)
+		
+		updating = false
+		showCategories()
+	}
+	
+	resetButton := widget.NewButton("Reset Filters", func() {
+		browseFilters = defaultBrowseFilters()
+		showFilters()
+		changed()
+	})
+	
+	showFilters()
+	
+	go func() {
+		categoriesResponse, err := apiClient.GetCategories(sims4GameID, 0, false)
+		if err != nil {
+			fmt.Printf("Failed to load categories: %v\n", err)
+		}
+		versionsResponse, err := apiClient.GetGameVersions(sims4GameID)
+		if err != nil {
+			fmt.Printf("Failed to load game versions: %v\n", err)
+		}
+		
+		fyne.Do(func() {
+			categories = categoriesResponse.Data
+			var classNames []string
+			for _, category := range categories {
+				if category.IsClass {
+					classIDs[category.Name] = category.ID
+					classNames = append(classNames, category.Name)
+				}
+			}
+			sort.Strings(classNames)
+			classSelect.Options = append([]string{anyClass}, classNames...)
+			versionSelect.Options = append([]string{anyVersion}, gameVersionOptions(versionsResponse.Data)...)
+			showFilters()
+		})
+	}()
+	
+	return container.NewVBox(
+		container.NewGridWithColumns(4, classSelect, categorySelect, versionSelect, authorEntry),
+		container.NewGridWithColumns(4, sortSelect, orderSelect, pageSizeSelect, resetButton),
+	)
+}
+
+func saveBrowseFilters() {
+	settings, err := LoadSettings()
+	if err != nil {
+		fmt.Printf("Failed to load settings: %v\n", err)
+		return
+	}
+	settings.BrowseFilters = browseFilters
+	if err := SaveSettings(settings); err != nil {
+		fmt.Printf("Failed to save browse filters: %v\n", err)
+	}
+}
+
+// gameVersionOptions lists every version once, newest first.
+func gameVersionOptions(types []VersionType) []string {
+	seen := make(map[string]bool)
+	var versions []string
+	for _, versionType := range types {
+		for _, version := range versionType.Versions {
+			if !seen[version] {
+				seen[version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return compareGameVersions(versions[i], versions[j]) > 0 })
+	return versions
+}
+
+// compareGameVersions compares dotted versions part by part as numbers, so
+// 1.100 comes after 1.99.
+func compareGameVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// filterOwnedPacks drops mods that need a pack we don't have when the owned
+// packs filter is on. Only categories and the summary are checked here, the
+// full description would cost a request per mod.
+func filterOwnedPacks(mods []Mod) []Mod {
+	if !ownedPacksOnly {
+		return mods
+	}
+	
+	settings, _ := LoadSettings()
+	
+	var filtered []Mod
+	for _, mod := range mods {
+		if len(MissingPacks(RequiredPacks(mod, ""), settings.OwnedPacks)) == 0 {
+			filtered = append(filtered, mod)
+		}
+	}
+	return filtered
+}
+
 func createModCard(mod Mod) fyne.CanvasObject {
 	nameLabel := widget.NewLabel(mod.Name)
 	nameLabel.TextStyle = fyne.TextStyle{Bold: true}
@@ -305,6 +642,11 @@ func showModDetails(mod Mod) {
 	
 	headerBox.Add(categoriesLabel)
 	
+	packsLabel := widget.NewLabel("")
+	packsLabel.Wrapping = fyne.TextWrapWord
+	packsLabel.Hide()
+	headerBox.Add(packsLabel)
+	
 	content := container.NewBorder(
 		headerBox,
 		container.NewHBox(layout.NewSpacer(), websiteButton, installButton),
@@ -316,6 +658,14 @@ func showModDetails(mod Mod) {
 	
 	go func() {
 		descResp, err := apiClient.GetModDescription(mod.ID)
+		
+		settings, _ := LoadSettings()
+		missing := MissingPacks(RequiredPacks(mod, descResp.Data), settings.OwnedPacks)
+		if len(missing) > 0 {
+			packsLabel.SetText("Warning: this mod looks like it needs packs you don't have: " + formatPackList(missing))
+			packsLabel.Show()
+		}
+		
 		if err != nil {
 			descriptionLabel.SetText("Failed to load description: " + err.Error())
 			return
@@ -350,6 +700,14 @@ func showModFiles(mod Mod) {
 			return
 		}
 		
+		description := ""
+		if descResp, err := apiClient.GetModDescription(mod.ID); err == nil {
+			description = descResp.Data
+		}
+		settings, _ := LoadSettings()
+		missing := MissingPacks(RequiredPacks(mod, description), settings.OwnedPacks)
+		installedPath, installed, _ := InstalledFile(mod.ID)
+		
 		filesList := widget.NewList(
 			func() int { return len(filesResp.Data) },
 			func() fyne.CanvasObject {
@@ -357,6 +715,7 @@ func showModFiles(mod Mod) {
 					widget.NewLabel("Filename"),
 					widget.NewLabel("Version"),
 					widget.NewButton("Download", func() {}),
+					widget.NewButton("Changes", func() {}),
 				)
 			},
 			func(id widget.ListItemID, item fyne.CanvasObject) {
@@ -376,9 +735,34 @@ func showModFiles(mod Mod) {
 				
 				downloadButton := container.Objects[2].(*widget.Button)
 				downloadButton.OnTapped = func() {
+					if len(missing) > 0 {
+						dialog.NewConfirm(
+							"Missing Packs",
+							fmt.Sprintf("%s looks like it needs packs you don't own:\n%s\n\nInstall anyway?", mod.Name, formatPackList(missing)),
+							func(confirmed bool) {
+								if confirmed {
+									filesWindow.Close()
+									downloadFile(mod, file)
+								}
+							},
+							filesWindow,
+						).Show()
+						return
+					}
 					filesWindow.Close() // i may or may not have forgot to add this when i first did this
 					downloadFile(mod, file)
 				}
+				
+				// packages we have an older version of can be compared before updating
+				changesButton := container.Objects[3].(*widget.Button)
+				if installedPath != "" && installed.FileID != file.ID && modExtension(file.FileName) == ".package" && modExtension(installedPath) == ".package" {
+					changesButton.OnTapped = func() {
+						showUpdateChanges(mod, file, installedPath)
+					}
+					changesButton.Show()
+				} else {
+					changesButton.Hide()
+				}
 			},
 		)
 		
@@ -397,74 +781,19 @@ func downloadFile(mod Mod, file File) {
 		return
 	}
 	
+	// never even download these
+	if file.FileStatus == FileStatusMalware {
+		dialog.ShowError(fmt.Errorf("%s: %s", file.FileName, fileStatusReason(file.FileStatus)), fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+	
 	filename := file.FileName
 	progressMessage := "Preparing download for " + filename
 	progress := dialog.NewProgress("Downloading", progressMessage, fyne.CurrentApp().Driver().AllWindows()[0])
 	progress.Show()
 	
 	go func() {
-		downloadURL := file.DownloadURL
-		
-		if downloadURL == "" {
-			urlResp, err := apiClient.GetModFileDownloadURL(mod.ID, file.ID)
-			if err == nil && urlResp.Data != "" {
-				downloadURL = urlResp.Data
-				fmt.Printf("Got a fucking download URL: %s\n", downloadURL)
-			}
-		}
-		
-		if downloadURL == "" {
-			fingerprints := []uint{}
-			
-			if file.FileFingerprint > 0 {
-				fingerprints = append(fingerprints, uint(file.FileFingerprint))
-			} else {
-				for i := range file.Modules {
-					if file.Modules[i].Fingerprint > 0 {
-						fingerprints = append(fingerprints, uint(file.Modules[i].Fingerprint))
-					}
-				}
-			}
-			
-			if len(fingerprints) == 0 {
-				fingerprints = append(fingerprints, uint(file.ID))
-			}
-			
-			fmt.Printf("Using fingerprints: %v\n", fingerprints)
-			
-			fingerprintResp, err := apiClient.MatchFingerprints(fingerprints)
-			if err == nil {
-				fmt.Printf("Found matches: Exact=%d, Partial=%d\n", 
-					len(fingerprintResp.Data.ExactMatches), len(fingerprintResp.Data.PartialMatches))
-				
-				for i := range fingerprintResp.Data.ExactMatches {
-					if fingerprintResp.Data.ExactMatches[i].File.DownloadURL != "" {
-						downloadURL = fingerprintResp.Data.ExactMatches[i].File.DownloadURL
-						break
-					}
-				}
-				
-				if downloadURL == "" && len(fingerprintResp.Data.PartialMatches) > 0 {
-					for i := range fingerprintResp.Data.PartialMatches {
-						if fingerprintResp.Data.PartialMatches[i].File.DownloadURL != "" {
-							downloadURL = fingerprintResp.Data.PartialMatches[i].File.DownloadURL
-							break
-						}
-					}
-				}
-			}
-		}
-		
-		if downloadURL == "" {
-			fmt.Printf("Fuck, no download URL. Let's try to make one...\n")
-			fileID := file.ID
-			thousands := fileID / 1000
-			remainder := fileID % 1000
-			
-			downloadURL = fmt.Sprintf("https://mediafilez.forgecdn.net/files/%d/%d/%s", 
-				thousands, remainder, file.FileName)
-			fmt.Printf("Made a URL: %s\n", downloadURL)
-		}
+		downloadURL := resolveDownloadURL(mod, file)
 		
 		if downloadURL == "" {
 			progress.Hide()
@@ -498,7 +827,7 @@ func downloadFile(mod Mod, file File) {
 		
 		fmt.Printf("Download URL: %s\n", downloadURL)
 		
-		err = ensureDirectoryExists(settings.ModsDirectory)
+		err = ensureModsDirectory(settings.ModsDirectory)
 		if err != nil {
 			progress.Hide()
 			dialog.ShowError(fmt.Errorf("failed to create mods directory: %w", err), fyne.CurrentApp().Driver().AllWindows()[0])
@@ -539,7 +868,13 @@ func downloadFile(mod Mod, file File) {
 		}
 		defer resp.Body.Close()
 		
-		targetPath := filepath.Join(settings.ModsDirectory, file.FileName)
+		// the file name comes from the API, don't trust it
+		targetPath, err := modInstallPath(settings.ModsDirectory, file.FileName)
+		if err != nil {
+			progress.Hide()
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
 		
 		if _, err := os.Stat(targetPath); err == nil {
 			progress.Hide()
@@ -548,22 +883,135 @@ func downloadFile(mod Mod, file File) {
 				"A file with the name "+file.FileName+" already exists. Do you want to overwrite it?",
 				func(confirmed bool) {
 					if confirmed {
-						downloadToFile(resp, targetPath, file.FileLength, progress)
+						downloadToFile(resp, settings.ModsDirectory, targetPath, mod, file, progress)
 					}
 				},
 				fyne.CurrentApp().Driver().AllWindows()[0],
 			)
 			confirmDialog.Show()
 		} else {
-			downloadToFile(resp, targetPath, file.FileLength, progress)
+			downloadToFile(resp, settings.ModsDirectory, targetPath, mod, file, progress)
 		}
 	}()
 	
 	progress.Show()
 }
 
-func downloadToFile(resp *http.Response, targetPath string, fileSize int64, progress *dialog.ProgressDialog) {
-	out, err := os.Create(targetPath)
+// resolveDownloadURL finds somewhere to download a file from: the API's URL,
+// the download URL endpoint, a fingerprint match or the CDN path as a last
+// resort.
+func resolveDownloadURL(mod Mod, file File) string {
+	downloadURL := file.DownloadURL
+	
+	if downloadURL == "" {
+		urlResp, err := apiClient.GetModFileDownloadURL(mod.ID, file.ID)
+		if err == nil && urlResp.Data != "" {
+			downloadURL = urlResp.Data
+			fmt.Printf("Got a fucking download URL: %s\n", downloadURL)
+		}
+	}
+	
+	if downloadURL == "" {
+		fingerprints := []uint{}
+		
+		if file.FileFingerprint > 0 {
+			fingerprints = append(fingerprints, uint(file.FileFingerprint))
+		} else {
+			for i := range file.Modules {
+				if file.Modules[i].Fingerprint > 0 {
+					fingerprints = append(fingerprints, uint(file.Modules[i].Fingerprint))
+				}
+			}
+		}
+		
+		if len(fingerprints) == 0 {
+			fingerprints = append(fingerprints, uint(file.ID))
+		}
+		
+		fmt.Printf("Using fingerprints: %v\n", fingerprints)
+		
+		fingerprintResp, err := apiClient.MatchFingerprints(fingerprints)
+		if err == nil {
+			fmt.Printf("Found matches: Exact=%d, Partial=%d\n", 
+				len(fingerprintResp.Data.ExactMatches), len(fingerprintResp.Data.PartialMatches))
+			
+			for i := range fingerprintResp.Data.ExactMatches {
+				if fingerprintResp.Data.ExactMatches[i].File.DownloadURL != "" {
+					downloadURL = fingerprintResp.Data.ExactMatches[i].File.DownloadURL
+					break
+				}
+			}
+			
+			if downloadURL == "" && len(fingerprintResp.Data.PartialMatches) > 0 {
+				for i := range fingerprintResp.Data.PartialMatches {
+					if fingerprintResp.Data.PartialMatches[i].File.DownloadURL != "" {
+						downloadURL = fingerprintResp.Data.PartialMatches[i].File.DownloadURL
+						break
+					}
+				}
+			}
+		}
+	}
+	
+	if downloadURL == "" {
+		fmt.Printf("Fuck, no download URL. Let's try to make one...\n")
+		fileID := file.ID
+		thousands := fileID / 1000
+		remainder := fileID % 1000
+		
+		downloadURL = fmt.Sprintf("https://mediafilez.forgecdn.net/files/%d/%d/%s", 
+			thousands, remainder, file.FileName)
+		fmt.Printf("Made a URL: %s\n", downloadURL)
+	}
+	
+	return downloadURL
+}
+
+// downloadToTemp fetches a file somewhere outside the Mods folder, for looking
+// at it before installing. The caller removes it.
+func downloadToTemp(mod Mod, file File) (string, error) {
+	if file.FileStatus == FileStatusMalware {
+		return "", fmt.Errorf("%s: %s", file.FileName, fileStatusReason(file.FileStatus))
+	}
+	
+	client := &http.Client{Timeout: 60 * time.Second}
+	req, err := http.NewRequest("GET", resolveDownloadURL(mod, file), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("User-Agent", "Sims4ModManager/1.0")
+	
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed: server returned status %d", resp.StatusCode)
+	}
+	
+	out, err := os.CreateTemp("", "sims4-update-*"+filepath.Ext(file.FileName))
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+func downloadToFile(resp *http.Response, modsDir, targetPath string, mod Mod, file File, progress *dialog.ProgressDialog) {
+	writePath, err := stagingPath(targetPath)
+	if err != nil {
+		progress.Hide()
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+	
+	out, err := os.Create(writePath)
 	if err != nil {
 		progress.Hide()
 		dialog.ShowError(fmt.Errorf("can't create the damn file: %w", err), fyne.CurrentApp().Driver().AllWindows()[0])
@@ -585,8 +1033,8 @@ func downloadToFile(resp *http.Response, targetPath string, fileSize int64, prog
 			}
 			
 			downloaded += int64(n)
-			if fileSize > 0 {
-				progress.SetValue(float64(downloaded) / float64(fileSize))
+			if file.FileLength > 0 {
+				progress.SetValue(float64(downloaded) / float64(file.FileLength))
 			} else {
 				progress.SetValue(0.5) // who the hell knows how big this file is
 			}
@@ -603,6 +1051,29 @@ func downloadToFile(resp *http.Response, targetPath string, fileSize int64, prog
 	}
 	
 	progress.Hide()
+	out.Close()
+	
+	outcome, entry, err := admitModFile(writePath, modsDir, targetPath, file.FileStatus)
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+	if outcome != AdmitQuarantined {
+		err := recordInstall(ManifestEntry{Path: targetPath, ModID: mod.ID, ModName: mod.Name, FileID: file.ID, FileName: file.FileName})
+		if err != nil {
+			fmt.Printf("Failed to update manifest: %v\n", err)
+		}
+	}
+	switch outcome {
+	case AdmitQuarantined:
+		showQuarantinedNotice(entry)
+		return
+	case AdmitQueued:
+		dialog.ShowInformation("Game Running", "Downloaded. The mod will be installed after the game exits.", fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+	
+	onModsChanged()
 	
 	successDialog := dialog.NewInformation(
 		"Got it!",
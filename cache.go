@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cache files and folders the game rebuilds on its own. Stale thumbnails and
+// sim textures in these are what make removed CC keep showing up.
+var gameCacheFiles = []string{
+	"localthumbcache.package",
+	"localsimtexturecache.package",
+	"avatarcache.package",
+}
+
+var gameCacheDirs = []string{
+	"cache",
+	"cachestr",
+	"lotcachedData",
+	"onlinethumbnailcache",
+}
+
+type CacheEntry struct {
+	Name  string
+	Path  string
+	Size  int64
+	IsDir bool
+}
+
+// ListGameCaches finds the cache files that exist in the documents folder.
+// Names are matched case-insensitively because wine doesn't care and some
+// installs end up with different casing.
+func ListGameCaches(modsDir string) ([]CacheEntry, error) {
+	docs := documentsDir(modsDir)
+
+	entries, err := os.ReadDir(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range gameCacheFiles {
+		wanted[strings.ToLower(name)] = false
+	}
+	for _, name := range gameCacheDirs {
+		wanted[strings.ToLower(name)] = true
+	}
+
+	var caches []CacheEntry
+	for _, entry := range entries {
+		isDir, ok := wanted[strings.ToLower(entry.Name())]
+		if !ok || isDir != entry.IsDir() {
+			continue
+		}
+
+		cache := CacheEntry{
+			Name:  entry.Name(),
+			Path:  filepath.Join(docs, entry.Name()),
+			IsDir: isDir,
+		}
+		cache.Size, err = diskUsage(cache.Path)
+		if err != nil {
+			return nil, err
+		}
+		caches = append(caches, cache)
+	}
+
+	return caches, nil
+}
+
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// CleanGameCaches deletes the cache files and empties the cache folders. The
+// folders themselves stay, the game expects them to be there. The running
+// game has them open, so it has to be closed first.
+func CleanGameCaches(modsDir string) (int64, error) {
+	if IsGameRunning() {
+		return 0, errGameRunning
+	}
+
+	caches, err := ListGameCaches(modsDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var freed int64
+	for _, cache := range caches {
+		if !cache.IsDir {
+			if err := os.Remove(cache.Path); err != nil {
+				return freed, fmt.Errorf("failed to delete %s: %w", cache.Name, err)
+			}
+			freed += cache.Size
+			continue
+		}
+
+		children, err := os.ReadDir(cache.Path)
+		if err != nil {
+			return freed, err
+		}
+		for _, child := range children {
+			if err := os.RemoveAll(filepath.Join(cache.Path, child.Name())); err != nil {
+				return freed, fmt.Errorf("failed to clean %s: %w", cache.Name, err)
+			}
+		}
+		freed += cache.Size
+	}
+
+	return freed, nil
+}
+
+// onModsChanged runs after anything touches the Mods folder (install, update,
+// enable/disable, removal, switching installs).
+func onModsChanged() {
+	settings, err := LoadSettings()
+	if err != nil || !settings.AutoCleanCache || IsGameRunning() {
+		return
+	}
+
+	freed, err := CleanGameCaches(settings.ModsDirectory)
+	if err != nil {
+		fmt.Printf("Failed to clean game caches: %v\n", err)
+		return
+	}
+	fmt.Printf("Cleaned game caches, freed %s\n", formatFileSize(freed))
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+const (
+	CategoryHair        = "Hair"
+	CategoryClothing    = "Clothing"
+	CategoryAccessories = "Accessories"
+	CategoryMakeup      = "Makeup"
+	CategorySkin        = "Skin"
+	CategoryCAS         = "Other CAS"
+	CategoryBuildBuy    = "Build/Buy"
+	CategoryTuning      = "Tuning"
+	CategoryLot         = "Lots & Rooms"
+	CategoryPose        = "Poses"
+	CategoryAnimation   = "Animations"
+	CategoryMixed       = "Mixed"
+	CategoryTranslation = "Translations"
+	CategoryScript      = "Script"
+	CategoryUnknown     = "Other"
+)
+
+// the order categories are listed in
+var categoryOrder = []string{
+	CategoryHair, CategoryClothing, CategoryAccessories, CategoryMakeup, CategorySkin, CategoryCAS,
+	CategoryBuildBuy, CategoryLot, CategoryTuning, CategoryPose, CategoryAnimation,
+	CategoryMixed, CategoryTranslation, CategoryScript, CategoryUnknown,
+}
+
+const (
+	resourceCASPart       = 0x034AEECB
+	resourceSkinTone      = 0x0354796A
+	resourceClip          = 0x6B20C4F3
+	resourceCatalogObject = 0x319E4F1D
+	resourceStringTable   = 0x220557DA
+)
+
+var buildBuyResources = map[uint32]bool{
+	0x319E4F1D: true, // COBJ catalog object
+	0xC0DB5AE7: true, // OBJD object definition
+	0xD5F0F921: true, // CWAL wall
+	0xB4F762C9: true, // CFLR floor
+	0x9A20CD1C: true, // CSTR stairs
+	0x1C1CF1F7: true, // CRAL railing
+	0xEBCBB16C: true, // CTPT terrain paint
+	0x2FAE983E: true, // CFND foundation
+	0xB0311D0F: true, // CRMT roof trim
+	0xA057811C: true, // CFRZ frieze
+	0x07936CE0: true, // CBLK block
+}
+
+var lotResources = map[uint32]bool{
+	0x3924DE26: true, // blueprint
+	0x370EFD6E: true, // room
+}
+
+// tuning resource types and what they are called
+var tuningResources = map[uint32]string{
+	0x03B33DDF: "generic tuning",
+	0x62E94D38: "combined tuning",
+	0x545AC67A: "SimData",
+	0x7DF2169C: "snippet",
+	0xE882D22F: "interaction",
+	0x0C772E27: "loot",
+	0x6017E896: "buff",
+	0xCB5FDDC7: "trait",
+	0x339BC5BD: "statistic",
+	0xB61DE6B4: "object tuning",
+	0xEB97F823: "recipe",
+	0x73996BEB: "career",
+	0x28B64675: "aspiration",
+}
+
+// poses are single frame clips, real animations are a lot bigger
+const poseClipMaxSize = 32 << 10
+
+// casCategory maps the CAS part body type to what people call it.
+func casCategory(bodyType int32) string {
+	switch {
+	case bodyType == 2 || bodyType == 28 || bodyType == 34: // hair, facial hair, brows
+		return CategoryHair
+	case bodyType == 1 || (bodyType >= 5 && bodyType <= 9) || bodyType == 36 || bodyType == 42:
+		return CategoryClothing
+	case bodyType >= 10 && bodyType <= 27:
+		return CategoryAccessories
+	case (bodyType >= 29 && bodyType <= 33) || bodyType == 35 || bodyType == 37:
+		return CategoryMakeup
+	case bodyType >= 38:
+		return CategorySkin
+	}
+	return CategoryCAS
+}
+
+var errShortCASPart = errors.New("CAS part is truncated")
+
+// CASPart is the little we need from a CASP resource.
+type CASPart struct {
+	BodyType int32
+	// string table keys of the name and description shown in CAS
+	TitleKey       uint32
+	DescriptionKey uint32
+}
+
+// parseCASPart digs the body type and name keys out of a CASP resource.
+// Everything in front of them is either fixed size or has its length written
+// down, the layout changed a bit between versions.
+func parseCASPart(data []byte) (CASPart, error) {
+	var part CASPart
+	r := bytes.NewReader(data)
+	read := func(v any) error {
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return errShortCASPart
+		}
+		return nil
+	}
+	skip := func(n int64) error {
+		if _, err := r.Seek(n, io.SeekCurrent); err != nil {
+			return errShortCASPart
+		}
+		return nil
+	}
+
+	var version uint32
+	if err := read(&version); err != nil {
+		return part, err
+	}
+	// tgi offset and preset count
+	if err := skip(8); err != nil {
+		return part, err
+	}
+
+	// the name is a 7 bit encoded byte length followed by UTF-16
+	nameLength, shift := 0, 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return part, errShortCASPart
+		}
+		nameLength |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	if err := skip(int64(nameLength)); err != nil {
+		return part, err
+	}
+
+	// sort priority, secondary sort index, property id, aural material, flags
+	fixed := int64(4 + 2 + 4 + 4 + 1)
+	if version >= 39 {
+		fixed++
+	}
+	fixed += 8 // excluded part flags
+	if version >= 41 {
+		fixed += 8
+	}
+	if version >= 37 {
+		fixed += 8 // excluded modifier regions
+	} else {
+		fixed += 4
+	}
+	if err := skip(fixed); err != nil {
+		return part, err
+	}
+
+	var tagCount uint32
+	if err := read(&tagCount); err != nil {
+		return part, err
+	}
+	tagSize := int64(4)
+	if version >= 37 {
+		tagSize = 6
+	}
+	if err := skip(int64(tagCount) * tagSize); err != nil {
+		return part, err
+	}
+
+	// price, then the name keys
+	if err := skip(4); err != nil {
+		return part, err
+	}
+	if err := read(&part.TitleKey); err != nil {
+		return part, err
+	}
+	if err := read(&part.DescriptionKey); err != nil {
+		return part, err
+	}
+
+	// create description key, then the unique texture space byte
+	fixed = 1
+	if version >= 43 {
+		fixed += 4
+	}
+	if err := skip(fixed); err != nil {
+		return part, err
+	}
+
+	if err := read(&part.BodyType); err != nil {
+		return part, err
+	}
+	return part, nil
+}
+
+// classifyPackage decides what a package is from the resource types in it.
+// casParts are the CAS parts that could be parsed, keyed by resource.
+func classifyPackage(pkg *DBPFPackage, casParts map[ResourceKey]CASPart) string {
+	var cas, buildBuy, lots, tuning, stringTables int
+	var clipSizes []int
+	casKinds := make(map[string]int)
+
+	for _, entry := range pkg.Entries {
+		switch {
+		case entry.Key.Type == resourceCASPart:
+			cas++
+			kind := CategoryCAS
+			if part, ok := casParts[entry.Key]; ok {
+				kind = casCategory(part.BodyType)
+			}
+			casKinds[kind]++
+		case entry.Key.Type == resourceSkinTone:
+			cas++
+			casKinds[CategorySkin]++
+		case entry.Key.Type == resourceClip:
+			clipSizes = append(clipSizes, int(entry.MemSize))
+		case buildBuyResources[entry.Key.Type]:
+			buildBuy++
+		case lotResources[entry.Key.Type]:
+			lots++
+		case tuningResources[entry.Key.Type] != "":
+			tuning++
+		case entry.Key.Type == resourceStringTable:
+			stringTables++
+		}
+	}
+
+	// objects carry their own tuning, so only CAS, objects and lots compete
+	kinds := 0
+	for _, n := range []int{cas, buildBuy, lots} {
+		if n > 0 {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return CategoryMixed
+	}
+
+	switch {
+	case cas > 0:
+		best, bestCount := CategoryCAS, 0
+		for kind, n := range casKinds {
+			if n > bestCount || (n == bestCount && kind < best) {
+				best, bestCount = kind, n
+			}
+		}
+		return best
+	case len(clipSizes) > 0:
+		// pose packs come with an object for the pose player, so clips win over
+		// build/buy
+		sort.Ints(clipSizes)
+		if clipSizes[len(clipSizes)/2] <= poseClipMaxSize {
+			return CategoryPose
+		}
+		return CategoryAnimation
+	case buildBuy > 0:
+		return CategoryBuildBuy
+	case lots > 0:
+		return CategoryLot
+	case tuning > 0:
+		return CategoryTuning
+	case stringTables > 0 && stringTables == len(pkg.Entries):
+		// nothing but text, somebody's translation of another mod
+		return CategoryTranslation
+	}
+	return CategoryUnknown
+}
+
+type CategoryTotal struct {
+	Category string
+	Files    int
+	Size     int64
+}
+
+// CategoryTotals adds up files and sizes per category, in categoryOrder.
+func CategoryTotals(mods []ModInfo) []CategoryTotal {
+	byCategory := make(map[string]*CategoryTotal)
+	for _, mod := range mods {
+		total, ok := byCategory[mod.Category]
+		if !ok {
+			total = &CategoryTotal{Category: mod.Category}
+			byCategory[mod.Category] = total
+		}
+		total.Files++
+		total.Size += mod.FileSize
+	}
+
+	var totals []CategoryTotal
+	for _, category := range categoryOrder {
+		if total, ok := byCategory[category]; ok {
+			totals = append(totals, *total)
+		}
+	}
+	return totals
+}
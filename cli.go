@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const cliUsage = `usage: sims4-mod-manager [command]
+
+Without a command the GUI starts.
+
+commands:
+  play [--force]   run the pre-launch checks and start the game
+                   (--force launches even when a check blocks it)
+  check            run the pre-launch checks only
+  undo             undo the last change to the Mods folder
+  duplicates       list identical and overlapping mod files
+  scripts          list the modules in each script mod and what's wrong with them
+  translations [language]
+                   list the mods with text missing in the game language
+  translations import <file>
+                   install a translation package next to the mod it translates
+  tuning <file> [instance]
+                   list the XML tuning in a package, or print one by instance
+  diff <old> <new> show what changed between two versions of a package
+  find <instance>  list the packages with a resource, by instance (hex or
+                   decimal) or type:group:instance
+  help             show this message
+`
+
+// runCLI handles the command line commands and returns the exit code.
+func runCLI(args []string) int {
+	settings, err := LoadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load settings: %v\n", err)
+		return 1
+	}
+	if settings.ApiKey != "" {
+		apiClient = NewApiClient(settings.ApiKey)
+	}
+
+	// the GUI may have been closed with changes still queued for the game to exit
+	switch args[0] {
+	case "play", "check", "scripts", "undo", "duplicates", "translations", "find":
+		applyQueuedChanges()
+	}
+
+	switch args[0] {
+	case "play":
+		force := len(args) > 1 && args[1] == "--force"
+
+		results, worst := RunLaunchChecks(settings)
+		fmt.Print(formatCheckResults(results))
+		if worst == SeverityBlocker && !force {
+			fmt.Fprintln(os.Stderr, "not launching, fix the blockers above or use --force")
+			return 1
+		}
+
+		if err := LaunchGame(settings); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println("launching The Sims 4")
+		return 0
+	case "check":
+		results, worst := RunLaunchChecks(settings)
+		fmt.Print(formatCheckResults(results))
+		if worst == SeverityBlocker {
+			return 1
+		}
+		return 0
+	case "scripts":
+		archives, err := ScanScriptArchives(settings.ModsDirectory)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to scan script mods: %v\n", err)
+			return 1
+		}
+		fmt.Print(formatScriptReport(settings.ModsDirectory, archives, FindScriptModuleConflicts(archives)))
+		return 0
+	case "undo":
+		operation, err := UndoLastOperation()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("undid %s\n", describeOperation(operation))
+		return 0
+	case "duplicates":
+		groups, err := FindDuplicates(settings.ModsDirectory)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to look for duplicates: %v\n", err)
+			return 1
+		}
+		fmt.Print(formatDuplicateGroups(settings.ModsDirectory, groups))
+		return 0
+	case "translations":
+		if len(args) > 1 && args[1] == "import" {
+			if len(args) < 3 {
+				fmt.Fprint(os.Stderr, cliUsage)
+				return 2
+			}
+			parent, err := FindTranslationParent(args[2], settings.ModsDirectory)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			outcome, entry, err := InstallTranslation(args[2], settings.ModsDirectory, parent)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			switch outcome {
+			case AdmitQuarantined:
+				fmt.Fprintf(os.Stderr, "quarantined: %s\n", entry.Reason)
+				return 1
+			case AdmitQueued:
+				fmt.Printf("translation of %s will be installed after the game exits\n", filepath.Base(parent))
+			default:
+				fmt.Printf("installed translation of %s\n", filepath.Base(parent))
+			}
+			return 0
+		}
+
+		language := settings.GameLanguage
+		if len(args) > 1 {
+			language = args[1]
+		}
+		missing, err := FindMissingTranslations(settings.ModsDirectory, language)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Print(formatMissingTranslations(settings.ModsDirectory, language, missing))
+		return 0
+	case "tuning":
+		if len(args) < 2 {
+			fmt.Fprint(os.Stderr, cliUsage)
+			return 2
+		}
+		tunings, err := ExtractTuning(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(args) > 2 {
+			instance, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(args[2]), "0x"), 16, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "bad instance %q, expected hex\n", args[2])
+				return 2
+			}
+			for _, tuning := range tunings {
+				if tuning.Key.Instance == instance {
+					fmt.Println(tuning.XML)
+					return 0
+				}
+			}
+			fmt.Fprintf(os.Stderr, "no tuning with instance %016X\n", instance)
+			return 1
+		}
+		for _, tuning := range tunings {
+			fmt.Println(tuning.Title())
+		}
+		return 0
+	case "diff":
+		if len(args) < 3 {
+			fmt.Fprint(os.Stderr, cliUsage)
+			return 2
+		}
+		changes, err := DiffPackages(args[1], args[2])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Print(formatPackageDiff(changes))
+		return 0
+	case "find":
+		if len(args) < 2 {
+			fmt.Fprint(os.Stderr, cliUsage)
+			return 2
+		}
+		matches, err := LookupResource(settings.ModsDirectory, args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Print(formatResourceMatches(settings.ModsDirectory, args[1], matches))
+		if len(matches) == 0 {
+			return 1
+		}
+		return 0
+	case "help", "-h", "--help":
+		fmt.Print(cliUsage)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], cliUsage)
+	return 2
+}
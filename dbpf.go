@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// .package files are DBPF 2.1 archives. The index says which resources (type,
+// group, instance) a package contains and where their data is.
+
+const dbpfHeaderSize = 96
+
+var errNotDBPF = errors.New("not a DBPF package")
+
+// the smallest an index entry gets, with type, group and instance high all
+// shared: instance low, offset, size and memory size
+const dbpfMinEntrySize = 16
+
+// deflate can't shrink data by more than about this much, a bigger memory
+// size than that is a broken index
+const zlibMaxRatio = 1032
+
+const (
+	compressionNone    = 0x0000
+	compressionZlib    = 0x5A42
+	compressionDeleted = 0xFFE0
+	compressionRefPack = 0xFFFF
+)
+
+type ResourceKey struct {
+	Type     uint32
+	Group    uint32
+	Instance uint64
+}
+
+func (k ResourceKey) String() string {
+	return fmt.Sprintf("%08X:%08X:%016X", k.Type, k.Group, k.Instance)
+}
+
+// keys are stored as their T:G:I string, a lot smaller than three JSON fields
+func (k ResourceKey) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *ResourceKey) UnmarshalText(text []byte) error {
+	key, err := ParseResourceKey(string(text))
+	if err != nil {
+		return err
+	}
+	*k = key
+	return nil
+}
+
+// ParseResourceKey reads a type:group:instance key in hex, the way S4PE and
+// most tools print them. Dashes work as separators too.
+func ParseResourceKey(text string) (ResourceKey, error) {
+	parts := strings.FieldsFunc(strings.TrimSpace(text), func(r rune) bool { return r == ':' || r == '-' })
+	if len(parts) != 3 {
+		return ResourceKey{}, fmt.Errorf("bad resource key %q, expected type:group:instance", text)
+	}
+
+	var values [3]uint64
+	for i, part := range parts {
+		part = strings.TrimPrefix(strings.ToLower(part), "0x")
+		bits := 32
+		if i == 2 {
+			bits = 64
+		}
+		v, err := strconv.ParseUint(part, 16, bits)
+		if err != nil {
+			return ResourceKey{}, fmt.Errorf("bad resource key %q: %w", text, err)
+		}
+		values[i] = v
+	}
+	return ResourceKey{Type: uint32(values[0]), Group: uint32(values[1]), Instance: values[2]}, nil
+}
+
+type DBPFEntry struct {
+	Key         ResourceKey
+	Offset      uint32
+	FileSize    uint32
+	MemSize     uint32
+	Compression uint16
+}
+
+type DBPFPackage struct {
+	Path    string
+	Entries []DBPFEntry
+}
+
+func (p *DBPFPackage) Keys() []ResourceKey {
+	keys := make([]ResourceKey, len(p.Entries))
+	for i, entry := range p.Entries {
+		keys[i] = entry.Key
+	}
+	return keys
+}
+
+// ReadDBPF reads the header and index of a package.
+func ReadDBPF(path string) (*DBPFPackage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, dbpfHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, errNotDBPF
+	}
+	if string(header[0:4]) != "DBPF" {
+		return nil, errNotDBPF
+	}
+	if major := binary.LittleEndian.Uint32(header[4:]); major != 2 {
+		return nil, fmt.Errorf("unsupported DBPF version %d", major)
+	}
+
+	count := binary.LittleEndian.Uint32(header[36:])
+	indexSize := binary.LittleEndian.Uint32(header[44:])
+	indexPos := int64(binary.LittleEndian.Uint32(header[64:]))
+	if indexPos == 0 {
+		// older writers only fill in the short position
+		indexPos = int64(binary.LittleEndian.Uint32(header[40:]))
+	}
+
+	pkg := &DBPFPackage{Path: path}
+	if count == 0 {
+		return pkg, nil
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if indexPos <= 0 || indexPos+int64(indexSize) > info.Size() || indexSize < 4 {
+		return nil, fmt.Errorf("%s: index outside the file", path)
+	}
+	// a corrupt count would otherwise size the entries slice
+	if count > indexSize/dbpfMinEntrySize {
+		return nil, fmt.Errorf("%s: index too small for %d entries", path, count)
+	}
+
+	index := make([]byte, indexSize)
+	if _, err := file.ReadAt(index, indexPos); err != nil {
+		return nil, err
+	}
+
+	entries, err := parseDBPFIndex(index, count)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	pkg.Entries = entries
+	return pkg, nil
+}
+
+// parseDBPFIndex decodes the index. The flags word says which of type, group
+// and instance high are shared by every entry and stored once up front.
+func parseDBPFIndex(index []byte, count uint32) ([]DBPFEntry, error) {
+	pos := 0
+	read := func() (uint32, error) {
+		if pos+4 > len(index) {
+			return 0, errors.New("index is truncated")
+		}
+		v := binary.LittleEndian.Uint32(index[pos:])
+		pos += 4
+		return v, nil
+	}
+
+	flags, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	var constants [3]uint32
+	for i := 0; i < 3; i++ {
+		if flags&(1<<i) != 0 {
+			if constants[i], err = read(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if count > uint32(len(index)/dbpfMinEntrySize) {
+		return nil, errors.New("index is truncated")
+	}
+	entries := make([]DBPFEntry, 0, count)
+	for n := uint32(0); n < count; n++ {
+		var fields [3]uint32
+		for i := 0; i < 3; i++ {
+			fields[i] = constants[i]
+			if flags&(1<<i) == 0 {
+				if fields[i], err = read(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		var values [4]uint32
+		for i := range values {
+			if values[i], err = read(); err != nil {
+				return nil, err
+			}
+		}
+
+		entry := DBPFEntry{
+			Key: ResourceKey{
+				Type:     fields[0],
+				Group:    fields[1],
+				Instance: uint64(fields[2])<<32 | uint64(values[0]),
+			},
+			Offset:   values[1],
+			FileSize: values[2] &^ 0x80000000,
+			MemSize:  values[3],
+		}
+
+		// the high bit on the size means compression info follows
+		if values[2]&0x80000000 != 0 {
+			extra, err := read()
+			if err != nil {
+				return nil, err
+			}
+			entry.Compression = uint16(extra)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// ReadResource returns the uncompressed data of one resource. The game writes
+// zlib, RefPack only shows up in very old tools' output and isn't supported.
+func (p *DBPFPackage) ReadResource(entry DBPFEntry) ([]byte, error) {
+	if entry.Compression == compressionDeleted {
+		return nil, fmt.Errorf("resource %s is deleted", entry.Key)
+	}
+
+	file, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if int64(entry.Offset)+int64(entry.FileSize) > info.Size() {
+		return nil, fmt.Errorf("resource %s is outside the file", entry.Key)
+	}
+	if entry.Compression == compressionZlib && int64(entry.MemSize) > int64(entry.FileSize)*zlibMaxRatio {
+		return nil, fmt.Errorf("resource %s: size %d can't be right", entry.Key, entry.MemSize)
+	}
+
+	data := make([]byte, entry.FileSize)
+	if _, err := file.ReadAt(data, int64(entry.Offset)); err != nil {
+		return nil, fmt.Errorf("resource %s: %w", entry.Key, err)
+	}
+
+	switch entry.Compression {
+	case compressionNone:
+		return data, nil
+	case compressionZlib:
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("resource %s: %w", entry.Key, err)
+		}
+		defer reader.Close()
+		return io.ReadAll(io.LimitReader(reader, int64(entry.MemSize)+1))
+	}
+	return nil, fmt.Errorf("resource %s: compression %04X isn't supported", entry.Key, entry.Compression)
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// the Diagnostics tab starts and stops the watcher, the game monitor starts
+// new sessions on it from its own goroutine
+var (
+	exceptionWatcherMu sync.Mutex
+	exceptionWatcher   *ExceptionWatcher
+)
+
+func currentExceptionWatcher() *ExceptionWatcher {
+	exceptionWatcherMu.Lock()
+	defer exceptionWatcherMu.Unlock()
+	return exceptionWatcher
+}
+
+func setExceptionWatcher(watcher *ExceptionWatcher) {
+	exceptionWatcherMu.Lock()
+	defer exceptionWatcherMu.Unlock()
+	exceptionWatcher = watcher
+}
+
+func setupDiagnosticsTab() fyne.CanvasObject {
+	var groups []ExceptionGroup
+	var timeline []ExceptionEvent
+
+	statusLabel := widget.NewLabel("Scan the game's exception logs to see which mods are throwing errors.")
+	statusLabel.Wrapping = fyne.TextWrapWord
+
+	groupsList := widget.NewList(
+		func() int { return len(groups) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(
+				nil, nil, nil, widget.NewButton("Details", func() {}),
+				container.NewVBox(
+					widget.NewLabel("Message"),
+					widget.NewLabel("Suspect"),
+				),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			group := groups[id]
+			row := item.(*fyne.Container)
+
+			labels := row.Objects[0].(*fyne.Container)
+			message := labels.Objects[0].(*widget.Label)
+			message.Truncation = fyne.TextTruncateEllipsis
+			message.SetText(fmt.Sprintf("%dx  %s", group.Count, orUnknownMessage(group.Message)))
+			labels.Objects[1].(*widget.Label).SetText(fmt.Sprintf("Likely cause: %s   Last seen: %s",
+				group.SuspectName(), group.LastSeen.Format("2006-01-02 15:04")))
+
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				showExceptionDetails(group)
+			}
+		},
+	)
+
+	scanButton := widget.NewButton("Scan Exception Logs", func() {
+		settings, err := LoadSettings()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		statusLabel.SetText("Scanning...")
+		go func() {
+			result, err := AnalyzeExceptions(settings.ModsDirectory)
+			fyne.Do(func() {
+				if err != nil {
+					statusLabel.SetText("Scan failed: " + err.Error())
+					return
+				}
+				groups = result
+				statusLabel.SetText(summarizeExceptionGroups(groups))
+				groupsList.Refresh()
+			})
+		}()
+	})
+
+	scriptsButton := widget.NewButton("Inspect Script Mods", func() {
+		showScriptInspection()
+	})
+
+	quarantineButton := widget.NewButton("Quarantine", func() {
+		showQuarantine()
+	})
+
+	findButton := widget.NewButton("Find Resource", func() {
+		showResourceLookup()
+	})
+
+	timelineLabel := widget.NewLabel("")
+
+	timelineList := widget.NewList(
+		func() int { return len(timeline) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(
+				nil, nil, nil, widget.NewButton("Trace", func() {}),
+				widget.NewLabel("Event"),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			// newest on top
+			event := timeline[len(timeline)-1-id]
+			row := item.(*fyne.Container)
+
+			label := row.Objects[0].(*widget.Label)
+			label.Truncation = fyne.TextTruncateEllipsis
+			label.SetText(fmt.Sprintf("%s  %s  %s", event.Time.Format("15:04:05"), eventSuspectName(event), orUnknownMessage(event.Message)))
+
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				showExceptionDetails(ExceptionGroup{
+					Message:       event.Message,
+					Trace:         event.Trace,
+					Frames:        ParseTraceFrames(event.Trace),
+					Count:         1,
+					Files:         []string{event.File},
+					LastSeen:      event.Time,
+					Suspect:       event.Suspect,
+					SuspectModule: event.Module,
+				})
+			}
+		},
+	)
+
+	updateTimeline := func() {
+		if watcher := currentExceptionWatcher(); watcher == nil {
+			timeline = nil
+			timelineLabel.SetText("Live watching is off.")
+		} else {
+			timeline = watcher.Timeline()
+			timelineLabel.SetText(fmt.Sprintf("This session: %d errors", len(timeline)))
+		}
+		timelineList.Refresh()
+	}
+
+	startWatching := func() error {
+		settings, err := LoadSettings()
+		if err != nil {
+			return err
+		}
+		watcher, err := StartExceptionWatcher(settings.ModsDirectory, func(event ExceptionEvent) {
+			fyne.CurrentApp().SendNotification(fyne.NewNotification(
+				"Sims 4 error from "+eventSuspectName(event),
+				orUnknownMessage(event.Message),
+			))
+			fyne.Do(updateTimeline)
+		})
+		if err != nil {
+			return err
+		}
+		setExceptionWatcher(watcher)
+		return nil
+	}
+
+	watchCheck := widget.NewCheck("Watch for new errors while playing", func(checked bool) {
+		if !checked {
+			if watcher := currentExceptionWatcher(); watcher != nil {
+				setExceptionWatcher(nil)
+				watcher.Stop()
+			}
+			updateTimeline()
+			return
+		}
+		if currentExceptionWatcher() != nil {
+			return
+		}
+		if err := startWatching(); err != nil {
+			timelineLabel.SetText("Can't watch the documents folder: " + err.Error())
+			return
+		}
+		updateTimeline()
+	})
+	watchCheck.SetChecked(true)
+
+	timelineBox := container.NewBorder(timelineLabel, nil, nil, nil, timelineList)
+
+	return container.NewBorder(
+		container.NewVBox(widget.NewLabel("Diagnostics"), statusLabel),
+		container.NewHBox(scanButton, scriptsButton, quarantineButton, findButton, watchCheck),
+		nil, nil,
+		container.NewVSplit(groupsList, timelineBox),
+	)
+}
+
+func showScriptInspection() {
+	settings, err := LoadSettings()
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+
+	archives, err := ScanScriptArchives(settings.ModsDirectory)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to scan script mods: %w", err), fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+	conflicts := FindScriptModuleConflicts(archives)
+
+	problems := len(conflicts)
+	for _, archive := range archives {
+		problems += len(archive.SourceOnly) + len(archive.BadBytecode)
+	}
+	summary := widget.NewLabel(fmt.Sprintf("%d script mods, %d problems. The game runs Python %s.", len(archives), problems, gamePythonVersion))
+
+	report := widget.NewLabel(formatScriptReport(settings.ModsDirectory, archives, conflicts))
+	report.TextStyle = fyne.TextStyle{Monospace: true}
+
+	scriptsWindow := fyne.CurrentApp().NewWindow("Script Mods")
+	scriptsWindow.Resize(fyne.NewSize(800, 600))
+	scriptsWindow.SetContent(container.NewBorder(summary, nil, nil, nil, container.NewScroll(report)))
+	scriptsWindow.Show()
+}
+
+// showResourceLookup finds the packages behind an instance ID from an error
+// report or a debug command.
+func showResourceLookup() {
+	settings, err := LoadSettings()
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+
+	lookupWindow := fyne.CurrentApp().NewWindow("Find Resource")
+	lookupWindow.Resize(fyne.NewSize(800, 450))
+
+	statusLabel := widget.NewLabel("Paste an instance ID (hex or decimal) or a full type:group:instance key.")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	resultLabel := widget.NewLabel("")
+	resultLabel.TextStyle = fyne.TextStyle{Monospace: true}
+
+	queryEntry := widget.NewEntry()
+	queryEntry.SetPlaceHolder("e.g. 0x00B2D882:00000000:1A2B3C4D5E6F7081 or 12345")
+
+	find := func() {
+		query := queryEntry.Text
+		if strings.TrimSpace(query) == "" {
+			return
+		}
+		statusLabel.SetText("Looking...")
+		go func() {
+			matches, err := LookupResource(settings.ModsDirectory, query)
+			fyne.Do(func() {
+				if err != nil {
+					statusLabel.SetText(err.Error())
+					resultLabel.SetText("")
+					return
+				}
+				files := make(map[string]bool)
+				for _, match := range matches {
+					files[match.Path] = true
+				}
+				statusLabel.SetText(fmt.Sprintf("%d resources in %d packages", len(matches), len(files)))
+				resultLabel.SetText(formatResourceMatches(settings.ModsDirectory, query, matches))
+			})
+		}()
+	}
+	queryEntry.OnSubmitted = func(string) { find() }
+
+	lookupWindow.SetContent(container.NewBorder(
+		container.NewVBox(statusLabel, container.NewBorder(nil, nil, nil, widget.NewButton("Find", find), queryEntry)),
+		nil, nil, nil,
+		container.NewScroll(resultLabel),
+	))
+	lookupWindow.Show()
+}
+
+func showQuarantinedNotice(entry QuarantineEntry) {
+	dialog.ShowInformation("Quarantined",
+		fmt.Sprintf("%s was not installed: %s.\n\nIt's in the quarantine (Diagnostics tab) if you trust it anyway.", entry.Name, entry.Reason),
+		fyne.CurrentApp().Driver().AllWindows()[0])
+}
+
+func showQuarantine() {
+	quarantineWindow := fyne.CurrentApp().NewWindow("Quarantine")
+	quarantineWindow.Resize(fyne.NewSize(800, 500))
+
+	var entries []QuarantineEntry
+	statusLabel := widget.NewLabel("")
+
+	var list *widget.List
+	reload := func() {
+		var err error
+		entries, err = ListQuarantine()
+		if err != nil {
+			statusLabel.SetText("Can't read the quarantine: " + err.Error())
+		} else {
+			statusLabel.SetText(fmt.Sprintf("%d files in quarantine", len(entries)))
+		}
+		list.Refresh()
+	}
+
+	list = widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(
+				nil, nil, nil,
+				container.NewHBox(widget.NewButton("Report", func() {}), widget.NewButton("Release", func() {}), widget.NewButton("Delete", func() {})),
+				container.NewVBox(widget.NewLabel("Name"), widget.NewLabel("Reason")),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			entry := entries[id]
+			row := item.(*fyne.Container)
+
+			labels := row.Objects[0].(*fyne.Container)
+			labels.Objects[0].(*widget.Label).SetText(entry.Name + "  (" + entry.Date.Format("2006-01-02 15:04") + ")")
+			reason := labels.Objects[1].(*widget.Label)
+			reason.Truncation = fyne.TextTruncateEllipsis
+			reason.SetText(entry.Reason)
+
+			buttons := row.Objects[1].(*fyne.Container)
+			buttons.Objects[0].(*widget.Button).OnTapped = func() {
+				report := widget.NewLabel(entry.Report())
+				report.TextStyle = fyne.TextStyle{Monospace: true}
+				scroll := container.NewScroll(report)
+				scroll.SetMinSize(fyne.NewSize(700, 400))
+				dialog.NewCustom("Quarantine Report", "Close", scroll, quarantineWindow).Show()
+			}
+			buttons.Objects[1].(*widget.Button).OnTapped = func() {
+				dialog.NewConfirm("Release", "Install "+entry.Name+" anyway? Only do this if you trust where it came from.", func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					queued, err := ReleaseQuarantined(entry)
+					if err != nil {
+						dialog.ShowError(err, quarantineWindow)
+					} else if queued {
+						dialog.ShowInformation("Game Running", entry.Name+" will be installed after the game exits.", quarantineWindow)
+					}
+					reload()
+				}, quarantineWindow).Show()
+			}
+			buttons.Objects[2].(*widget.Button).OnTapped = func() {
+				dialog.NewConfirm("Delete", "Delete "+entry.Name+" for good?", func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					if err := DeleteQuarantined(entry); err != nil {
+						dialog.ShowError(err, quarantineWindow)
+					}
+					reload()
+				}, quarantineWindow).Show()
+			}
+		},
+	)
+
+	scanButton := widget.NewButton("Scan Installed Scripts", func() {
+		settings, err := LoadSettings()
+		if err != nil {
+			dialog.ShowError(err, quarantineWindow)
+			return
+		}
+
+		progress := dialog.NewProgressInfinite("Quarantine", "Scanning script mods...", quarantineWindow)
+		progress.Show()
+		go func() {
+			flagged, err := ScanInstalledScripts(settings.ModsDirectory)
+			fyne.Do(func() {
+				progress.Hide()
+				if err != nil {
+					dialog.ShowError(err, quarantineWindow)
+					return
+				}
+				if len(flagged) == 0 {
+					dialog.ShowInformation("Quarantine", "Nothing suspicious in the installed script mods.", quarantineWindow)
+					return
+				}
+
+				var paths []string
+				var text strings.Builder
+				for path, findings := range flagged {
+					paths = append(paths, path)
+					fmt.Fprintf(&text, "%s\n%s\n", path, formatFindings(findings))
+				}
+				report := widget.NewLabel(text.String())
+				report.TextStyle = fyne.TextStyle{Monospace: true}
+				scroll := container.NewScroll(report)
+				scroll.SetMinSize(fyne.NewSize(700, 400))
+
+				dialog.NewCustomConfirm("Suspicious Script Mods", "Quarantine All", "Leave Them", scroll, func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					for _, path := range paths {
+						if err := quarantineInstalled(path, flagged[path]); err != nil {
+							dialog.ShowError(err, quarantineWindow)
+							break
+						}
+					}
+					reload()
+				}, quarantineWindow).Show()
+			})
+		}()
+	})
+
+	reload()
+
+	quarantineWindow.SetContent(container.NewBorder(
+		statusLabel,
+		container.NewHBox(scanButton),
+		nil, nil,
+		list,
+	))
+	quarantineWindow.Show()
+}
+
+func eventSuspectName(event ExceptionEvent) string {
+	if event.Suspect == "" {
+		return "unknown mod"
+	}
+	return filepath.Base(event.Suspect)
+}
+
+func summarizeExceptionGroups(groups []ExceptionGroup) string {
+	if len(groups) == 0 {
+		return "No exception reports found. Either everything works or the game hasn't written any."
+	}
+
+	total := 0
+	suspects := make(map[string]int)
+	for _, group := range groups {
+		total += group.Count
+		if group.Suspect != "" {
+			suspects[group.SuspectName()] += group.Count
+		}
+	}
+
+	text := fmt.Sprintf("%d errors in %d distinct traces.", total, len(groups))
+	if len(suspects) > 0 {
+		var parts []string
+		for name, count := range suspects {
+			parts = append(parts, fmt.Sprintf("%s (%d)", name, count))
+		}
+		text += " Mods involved: " + strings.Join(parts, ", ")
+	}
+	return text
+}
+
+func orUnknownMessage(message string) string {
+	if message == "" {
+		return "(no message)"
+	}
+	return message
+}
+
+func showExceptionDetails(group ExceptionGroup) {
+	detailsWindow := fyne.CurrentApp().NewWindow("Exception Details")
+	detailsWindow.Resize(fyne.NewSize(800, 600))
+
+	header := fmt.Sprintf("%s\n\nSeen %d times, last on %s\nCategory: %s\nLikely cause: %s",
+		orUnknownMessage(group.Message), group.Count, group.LastSeen.Format("2006-01-02 15:04:05"),
+		group.Category, group.SuspectName())
+	if group.Suspect != "" {
+		header += fmt.Sprintf("\nArchive: %s\nModule: %s", group.Suspect, group.SuspectModule)
+	}
+	header += "\nReports: " + strings.Join(group.Files, ", ")
+
+	headerLabel := widget.NewLabel(header)
+	headerLabel.Wrapping = fyne.TextWrapWord
+
+	var frames strings.Builder
+	for _, frame := range group.Frames {
+		owner := "game"
+		if frame.Archive != "" {
+			owner = frame.Module + " in " + frame.Archive
+		}
+		fmt.Fprintf(&frames, "%s:%d in %s  [%s]\n", frame.Path, frame.Line, frame.Func, owner)
+	}
+
+	traceText := widget.NewLabel(frames.String() + "\n" + group.Trace)
+	traceText.TextStyle = fyne.TextStyle{Monospace: true}
+
+	detailsWindow.SetContent(container.NewBorder(
+		headerLabel, nil, nil, nil,
+		container.NewScroll(traceText),
+	))
+	detailsWindow.Show()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const sims4SteamAppID = "1222670"
+
+var vdfPathPattern = regexp.MustCompile(`^\s*"path"\s+"(.*)"\s*$`)
+
+// where the game lives inside a plain wine prefix, depending on which
+// launcher installed it
+var prefixGameDirs = [][]string{
+	{"drive_c", "Program Files", "EA Games", "The Sims 4"},
+	{"drive_c", "Program Files (x86)", "Origin Games", "The Sims 4"},
+	{"drive_c", "Program Files", "Origin Games", "The Sims 4"},
+}
+
+// DiscoverGameInstalls looks through Steam libraries and the Lutris, Heroic and
+// Bottles prefixes for anything that looks like a Sims 4 install or a Sims 4
+// documents folder.
+func DiscoverGameInstalls() []GameInstall {
+	home := os.Getenv("HOME")
+
+	var installs []GameInstall
+	seen := make(map[string]bool)
+	add := func(install GameInstall) {
+		key := install.GameDirectory + "|" + install.ModsDirectory
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		installs = append(installs, install)
+	}
+
+	for _, library := range steamLibraries(home) {
+		install := GameInstall{Name: "Steam (" + library + ")", Source: "steam"}
+
+		gameDir := filepath.Join(library, "steamapps", "common", "The Sims 4")
+		if dirExists(gameDir) {
+			install.GameDirectory = gameDir
+		}
+
+		prefix := filepath.Join(library, "steamapps", "compatdata", sims4SteamAppID, "pfx")
+		for _, docs := range prefixDocumentsDirs(prefix) {
+			install.ModsDirectory = filepath.Join(docs, "Mods")
+			break
+		}
+
+		if install.GameDirectory != "" || install.ModsDirectory != "" {
+			add(install)
+		}
+	}
+
+	for _, prefix := range lutrisPrefixes(home) {
+		if install, ok := installFromPrefix("Lutris", prefix); ok {
+			add(install)
+		}
+	}
+
+	for _, prefix := range heroicPrefixes(home) {
+		if install, ok := installFromPrefix("Heroic", prefix); ok {
+			add(install)
+		}
+	}
+
+	for _, prefix := range bottlesPrefixes(home) {
+		if install, ok := installFromPrefix("Bottles", prefix); ok {
+			add(install)
+		}
+	}
+
+	return installs
+}
+
+func installFromPrefix(source, prefix string) (GameInstall, bool) {
+	install := GameInstall{
+		Name:   source + " (" + prefix + ")",
+		Source: strings.ToLower(source),
+	}
+
+	for _, parts := range prefixGameDirs {
+		gameDir := filepath.Join(append([]string{prefix}, parts...)...)
+		if dirExists(gameDir) {
+			install.GameDirectory = gameDir
+			break
+		}
+	}
+
+	for _, docs := range prefixDocumentsDirs(prefix) {
+		install.ModsDirectory = filepath.Join(docs, "Mods")
+		break
+	}
+
+	return install, install.GameDirectory != "" || install.ModsDirectory != ""
+}
+
+// prefixDocumentsDirs returns the Sims 4 documents folders of every wine user
+// in a prefix. Proton always uses steamuser, the others use the login name.
+func prefixDocumentsDirs(prefix string) []string {
+	var dirs []string
+
+	users, err := os.ReadDir(filepath.Join(prefix, "drive_c", "users"))
+	if err != nil {
+		return nil
+	}
+
+	for _, user := range users {
+		if !user.IsDir() || user.Name() == "Public" {
+			continue
+		}
+		for _, documents := range []string{"Documents", "My Documents"} {
+			docs := filepath.Join(prefix, "drive_c", "users", user.Name(), documents, "Electronic Arts", "The Sims 4")
+			if dirExists(docs) {
+				dirs = append(dirs, docs)
+			}
+		}
+	}
+
+	return dirs
+}
+
+func steamLibraries(home string) []string {
+	roots := []string{
+		filepath.Join(home, ".steam", "steam"),
+		filepath.Join(home, ".local", "share", "Steam"),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+	}
+
+	var libraries []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil || seen[resolved] {
+			return
+		}
+		seen[resolved] = true
+		libraries = append(libraries, path)
+	}
+
+	for _, root := range roots {
+		if !dirExists(root) {
+			continue
+		}
+		add(root)
+		for _, path := range parseLibraryFolders(filepath.Join(root, "steamapps", "libraryfolders.vdf")) {
+			add(path)
+		}
+	}
+
+	return libraries
+}
+
+// parseLibraryFolders pulls the library paths out of libraryfolders.vdf. We
+// only need the "path" keys so there's no point writing a real vdf parser.
+func parseLibraryFolders(vdfPath string) []string {
+	file, err := os.Open(vdfPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if m := vdfPathPattern.FindStringSubmatch(scanner.Text()); m != nil {
+			paths = append(paths, strings.ReplaceAll(m[1], `\\`, `\`))
+		}
+	}
+	return paths
+}
+
+func lutrisPrefixes(home string) []string {
+	var prefixes []string
+
+	configs, _ := filepath.Glob(filepath.Join(home, ".config", "lutris", "games", "*.yml"))
+	configs2, _ := filepath.Glob(filepath.Join(home, ".local", "share", "lutris", "games", "*.yml"))
+	for _, config := range append(configs, configs2...) {
+		if !strings.Contains(strings.ToLower(filepath.Base(config)), "sims") {
+			continue
+		}
+		data, err := os.ReadFile(config)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "prefix:") {
+				prefixes = append(prefixes, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "prefix:")), `"'`))
+			}
+		}
+	}
+
+	// lutris' default install location
+	games, _ := os.ReadDir(filepath.Join(home, "Games"))
+	for _, game := range games {
+		if game.IsDir() && strings.Contains(strings.ToLower(game.Name()), "sims") {
+			prefixes = append(prefixes, filepath.Join(home, "Games", game.Name()))
+		}
+	}
+
+	return prefixes
+}
+
+func heroicPrefixes(home string) []string {
+	var prefixes []string
+
+	configs, _ := filepath.Glob(filepath.Join(home, ".config", "heroic", "GamesConfig", "*.json"))
+	for _, config := range configs {
+		data, err := os.ReadFile(config)
+		if err != nil {
+			continue
+		}
+		// games sit next to plain values like "version" and "explicit", only
+		// the objects are games
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(data, &entries); err != nil {
+			continue
+		}
+		for _, raw := range entries {
+			var game struct {
+				WinePrefix string `json:"winePrefix"`
+			}
+			if len(raw) == 0 || raw[0] != '{' || json.Unmarshal(raw, &game) != nil {
+				continue
+			}
+			if game.WinePrefix != "" {
+				prefixes = append(prefixes, game.WinePrefix)
+			}
+		}
+	}
+
+	defaults, _ := filepath.Glob(filepath.Join(home, "Games", "Heroic", "Prefixes", "*"))
+	return append(prefixes, defaults...)
+}
+
+func bottlesPrefixes(home string) []string {
+	native, _ := filepath.Glob(filepath.Join(home, ".local", "share", "bottles", "bottles", "*"))
+	flatpak, _ := filepath.Glob(filepath.Join(home, ".var", "app", "com.usebottles.bottles", "data", "bottles", "bottles", "*"))
+	return append(native, flatpak...)
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	DuplicateIdentical     = "identical"
+	DuplicateSameResources = "same resources"
+	DuplicateSuperset      = "overlapping resources"
+)
+
+type DuplicateFile struct {
+	FileFingerprint
+	// resources in the package, 0 for scripts
+	Resources  int
+	InManifest bool
+	ModName    string
+}
+
+// DuplicateGroup is a set of files where all but one can go. Identical
+// groups are byte for byte the same, the others are packages whose resources
+// are all contained in the package with the most of them.
+type DuplicateGroup struct {
+	Kind  string
+	Files []DuplicateFile
+}
+
+// Newest is the most recently modified file, the usual pick since it's most
+// likely the latest version.
+func (g DuplicateGroup) Newest() string {
+	newest := g.Files[0]
+	for _, file := range g.Files[1:] {
+		if file.ModTime.After(newest.ModTime) {
+			newest = file
+		}
+	}
+	return newest.Path
+}
+
+// Recommended is the file to keep: the newest, except that overlapping
+// groups keep the newest of the packages holding every resource of the group.
+func (g DuplicateGroup) Recommended() string {
+	if g.Kind != DuplicateSuperset {
+		return g.Newest()
+	}
+	most := 0
+	for _, file := range g.Files {
+		most = max(most, file.Resources)
+	}
+	var pick *DuplicateFile
+	for i, file := range g.Files {
+		if file.Resources == most && (pick == nil || file.ModTime.After(pick.ModTime)) {
+			pick = &g.Files[i]
+		}
+	}
+	return pick.Path
+}
+
+// ManifestFile is the newest of the files the manager installed itself.
+func (g DuplicateGroup) ManifestFile() (string, bool) {
+	var pick *DuplicateFile
+	for i, file := range g.Files {
+		if file.InManifest && (pick == nil || file.ModTime.After(pick.ModTime)) {
+			pick = &g.Files[i]
+		}
+	}
+	if pick == nil {
+		return "", false
+	}
+	return pick.Path, true
+}
+
+// Reclaimable is the disk space freed by keeping only keep.
+func (g DuplicateGroup) Reclaimable(keep string) int64 {
+	var total int64
+	for _, file := range g.Files {
+		if file.Path != keep {
+			total += file.Size
+		}
+	}
+	return total
+}
+
+type resourceSet map[ResourceKey]struct{}
+
+// FindDuplicates groups the installed files by their sha256, then compares
+// the resource keys of the remaining packages. Both come from the mod index.
+func FindDuplicates(modsDir string) ([]DuplicateGroup, error) {
+	files, err := CalculateFileFingerprintsForDir(modsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := ManifestByPath()
+	if err != nil {
+		fmt.Printf("Failed to read manifest: %v\n", err)
+	}
+
+	toDuplicate := func(file FileFingerprint, resources int) DuplicateFile {
+		entry, ok := manifest[file.Path]
+		return DuplicateFile{FileFingerprint: file, Resources: resources, InManifest: ok, ModName: entry.ModName}
+	}
+
+	byHash := make(map[string][]FileFingerprint)
+	var order []string
+	for _, file := range files {
+		key := file.SHA256
+		if key == "" {
+			// changed while it was hashed, it's identical to nothing we know of
+			key = "unhashed:" + file.Path
+		}
+		if _, ok := byHash[key]; !ok {
+			order = append(order, key)
+		}
+		byHash[key] = append(byHash[key], file)
+	}
+
+	var groups []DuplicateGroup
+	// one file per distinct content goes on to the resource comparison
+	var packages []FileFingerprint
+	var sets []resourceSet
+	for _, key := range order {
+		same := byHash[key]
+
+		resources := 0
+		if keys := indexedKeys(same[0].Path); len(keys) > 0 {
+			set := make(resourceSet, len(keys))
+			for _, key := range keys {
+				set[key] = struct{}{}
+			}
+			resources = len(set)
+			packages = append(packages, same[0])
+			sets = append(sets, set)
+		}
+
+		if len(same) > 1 {
+			group := DuplicateGroup{Kind: DuplicateIdentical}
+			for _, file := range same {
+				group.Files = append(group.Files, toDuplicate(file, resources))
+			}
+			groups = append(groups, group)
+		}
+	}
+
+	groups = append(groups, groupOverlappingPackages(packages, sets, toDuplicate)...)
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Reclaimable(groups[i].Recommended()) > groups[j].Reclaimable(groups[j].Recommended())
+	})
+	return groups, nil
+}
+
+// groupOverlappingPackages groups every package with a package that contains
+// all of its resources. Only packages that no other package contains start a
+// group, so the package a group is built around holds everything in it.
+func groupOverlappingPackages(packages []FileFingerprint, sets []resourceSet, toDuplicate func(FileFingerprint, int) DuplicateFile) []DuplicateGroup {
+	holders := make(map[ResourceKey][]int)
+	for i, set := range sets {
+		for key := range set {
+			holders[key] = append(holders[key], i)
+		}
+	}
+
+	// containers[i] are the other packages with all of i's resources
+	containers := make([][]int, len(packages))
+	for i, set := range sets {
+		// only packages holding the rarest key can contain all of them
+		var candidates []int
+		for key := range set {
+			if candidates == nil || len(holders[key]) < len(candidates) {
+				candidates = holders[key]
+			}
+		}
+
+		for _, j := range candidates {
+			if j == i || len(sets[j]) < len(set) {
+				continue
+			}
+			contained := true
+			for key := range set {
+				if _, ok := sets[j][key]; !ok {
+					contained = false
+					break
+				}
+			}
+			if contained {
+				containers[i] = append(containers[i], j)
+			}
+		}
+	}
+
+	// a package is a root unless a bigger one contains it, of packages with the
+	// same resources the first one is
+	dominates := func(j, i int) bool {
+		return len(sets[j]) > len(sets[i]) || j < i
+	}
+	isRoot := make([]bool, len(packages))
+	for i := range packages {
+		isRoot[i] = true
+		for _, j := range containers[i] {
+			if dominates(j, i) {
+				isRoot[i] = false
+				break
+			}
+		}
+	}
+
+	// everything else joins the biggest root containing it, containment is
+	// transitive so there always is one
+	members := make(map[int][]int)
+	for i := range packages {
+		if isRoot[i] {
+			members[i] = append(members[i], i)
+			continue
+		}
+		root := -1
+		for _, j := range containers[i] {
+			if isRoot[j] && (root == -1 || dominates(j, root)) {
+				root = j
+			}
+		}
+		members[root] = append(members[root], i)
+	}
+
+	var groups []DuplicateGroup
+	for _, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+
+		group := DuplicateGroup{Kind: DuplicateSameResources}
+		for _, i := range indexes {
+			if len(sets[i]) != len(sets[indexes[0]]) {
+				group.Kind = DuplicateSuperset
+			}
+			group.Files = append(group.Files, toDuplicate(packages[i], len(sets[i])))
+		}
+		sort.Slice(group.Files, func(a, b int) bool { return group.Files[a].Path < group.Files[b].Path })
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// checkCovered makes sure nothing is lost by trashing file and keeping keep:
+// identical files have to still be byte for byte the same, packages can only
+// go when keep has every one of their resources. Both are checked on disk
+// again, the scan may be old.
+func checkCovered(kind, keep, file string) error {
+	if kind == DuplicateIdentical {
+		_, keepSum, err := hashModFile(keep)
+		if err != nil {
+			return err
+		}
+		_, fileSum, err := hashModFile(file)
+		if err != nil {
+			return err
+		}
+		if keepSum != fileSum {
+			return fmt.Errorf("%s isn't identical to %s anymore", filepath.Base(file), filepath.Base(keep))
+		}
+		return nil
+	}
+
+	kept, err := ReadDBPF(keep)
+	if err != nil {
+		return err
+	}
+	pkg, err := ReadDBPF(file)
+	if err != nil {
+		return err
+	}
+	have := make(resourceSet, len(kept.Entries))
+	for _, key := range kept.Keys() {
+		have[key] = struct{}{}
+	}
+	for _, key := range pkg.Keys() {
+		if _, ok := have[key]; !ok {
+			return fmt.Errorf("%s has resources %s doesn't, like %s", filepath.Base(file), filepath.Base(keep), key)
+		}
+	}
+	return nil
+}
+
+// ResolveDuplicateGroup sends everything but keep to the trash and returns
+// the space freed.
+func ResolveDuplicateGroup(group DuplicateGroup, keep string) (int64, error) {
+	if IsGameRunning() {
+		return 0, errGameRunning
+	}
+
+	// refuse before anything is trashed
+	for _, file := range group.Files {
+		if file.Path == keep {
+			continue
+		}
+		if err := checkCovered(group.Kind, keep, file.Path); err != nil {
+			return 0, fmt.Errorf("not resolving the group: %w", err)
+		}
+	}
+
+	var freed int64
+	for _, file := range group.Files {
+		if file.Path == keep {
+			continue
+		}
+		if err := journaledDelete(0, file.Path); err != nil {
+			return freed, err
+		}
+		freed += file.Size
+	}
+
+	onModsChanged()
+	return freed, nil
+}
+
+func formatDuplicateFile(modsDir string, file DuplicateFile) string {
+	name := file.Path
+	if rel, err := filepath.Rel(modsDir, file.Path); err == nil {
+		name = rel
+	}
+
+	details := []string{file.ModTime.Format("2006-01-02"), formatFileSize(file.Size)}
+	if file.Resources > 0 {
+		details = append(details, fmt.Sprintf("%d resources", file.Resources))
+	}
+	if file.InManifest && file.ModName != "" {
+		details = append(details, "installed from CurseForge: "+file.ModName)
+	} else if file.InManifest {
+		details = append(details, "installed with the manager")
+	}
+	return name + "  (" + strings.Join(details, ", ") + ")"
+}
+
+func showDuplicates(list *widget.List) {
+	settings, err := LoadSettings()
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+
+	duplicatesWindow := fyne.CurrentApp().NewWindow("Duplicates")
+	duplicatesWindow.Resize(fyne.NewSize(850, 550))
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	groupsBox := container.NewVBox()
+
+	var scan func()
+
+	resolve := func(group DuplicateGroup, keep string) {
+		message := fmt.Sprintf("Keep %s and move the other %d files to the trash?", filepath.Base(keep), len(group.Files)-1)
+		dialog.NewConfirm("Resolve Duplicates", message, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			freed, err := ResolveDuplicateGroup(group, keep)
+			if err != nil {
+				dialog.ShowError(err, duplicatesWindow)
+			} else {
+				dialog.ShowInformation("Duplicates", "Reclaimed "+formatFileSize(freed), duplicatesWindow)
+			}
+			refreshModsList(list)
+			scan()
+		}, duplicatesWindow).Show()
+	}
+
+	showGroups := func(groups []DuplicateGroup) {
+		groupsBox.Objects = nil
+
+		var reclaimable int64
+		for _, group := range groups {
+			keep := group.Recommended()
+			reclaimable += group.Reclaimable(keep)
+
+			var lines []string
+			for _, file := range group.Files {
+				lines = append(lines, formatDuplicateFile(settings.ModsDirectory, file))
+			}
+			filesLabel := widget.NewLabel(strings.Join(lines, "\n"))
+			filesLabel.Wrapping = fyne.TextWrapWord
+
+			keepLabel := "Keep Newest"
+			if group.Kind == DuplicateSuperset {
+				keepLabel = "Keep the Package With Everything"
+			}
+			keepButton := widget.NewButton(keepLabel, func() { resolve(group, keep) })
+			manifestButton := widget.NewButton("Keep the One in the Manifest", func() {})
+			if manifestKeep, ok := group.ManifestFile(); ok {
+				manifestButton.OnTapped = func() { resolve(group, manifestKeep) }
+			} else {
+				manifestButton.Disable()
+			}
+
+			groupsBox.Add(widget.NewCard(
+				fmt.Sprintf("%d files, %s", len(group.Files), group.Kind),
+				"Keeping "+filepath.Base(keep)+" frees "+formatFileSize(group.Reclaimable(keep)),
+				container.NewVBox(filesLabel, container.NewHBox(keepButton, manifestButton)),
+			))
+		}
+
+		if len(groups) == 0 {
+			statusLabel.SetText("No duplicates found.")
+		} else {
+			statusLabel.SetText(fmt.Sprintf("%d groups of duplicates, about %s can be reclaimed.", len(groups), formatFileSize(reclaimable)))
+		}
+		groupsBox.Refresh()
+	}
+
+	scan = func() {
+		statusLabel.SetText("Hashing files and reading package indexes...")
+		go func() {
+			groups, err := FindDuplicates(settings.ModsDirectory)
+			fyne.Do(func() {
+				if err != nil {
+					statusLabel.SetText("Scan failed: " + err.Error())
+					return
+				}
+				showGroups(groups)
+			})
+		}()
+	}
+
+	duplicatesWindow.SetContent(container.NewBorder(
+		statusLabel,
+		container.NewHBox(widget.NewButton("Scan Again", scan)),
+		nil, nil,
+		container.NewVScroll(groupsBox),
+	))
+	duplicatesWindow.Show()
+
+	scan()
+}
+
+// formatDuplicateGroups is the plain text version for the command line.
+func formatDuplicateGroups(modsDir string, groups []DuplicateGroup) string {
+	if len(groups) == 0 {
+		return "No duplicates found.\n"
+	}
+
+	var b strings.Builder
+	for _, group := range groups {
+		keep := group.Recommended()
+		fmt.Fprintf(&b, "%d files, %s, keeping the marked one frees %s\n", len(group.Files), group.Kind, formatFileSize(group.Reclaimable(keep)))
+		for _, file := range group.Files {
+			marker := " "
+			if file.Path == keep {
+				marker = "*"
+			}
+			fmt.Fprintf(&b, "  %s %s\n", marker, formatDuplicateFile(modsDir, file))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var traceFramePattern = regexp.MustCompile(`File "([^"]+)", line (\d+), in (\S+)`)
+
+// ExceptionReport is one <report> from a lastException file. A single file can
+// hold a lot of them when something throws on every tick.
+type ExceptionReport struct {
+	File           string
+	Time           time.Time
+	CreateTime     string
+	BuildSignature string
+	Category       string
+	Trace          string
+}
+
+type TraceFrame struct {
+	Path    string
+	Line    int
+	Func    string
+	Archive string
+	Module  string
+}
+
+type ExceptionGroup struct {
+	Key      string
+	Message  string
+	Category string
+	Trace    string
+	Frames   []TraceFrame
+	Count    int
+	Files    []string
+	LastSeen time.Time
+	// the mod archive closest to where the exception was raised, empty when
+	// the trace only goes through game code
+	Suspect       string
+	SuspectModule string
+}
+
+func (g ExceptionGroup) SuspectName() string {
+	if g.Suspect == "" {
+		return "unknown (game code only)"
+	}
+	return filepath.Base(g.Suspect)
+}
+
+// FindExceptionFiles lists the exception reports the game left in the
+// documents folder, newest first.
+func FindExceptionFiles(modsDir string) ([]string, error) {
+	docs := documentsDir(modsDir)
+
+	var files []string
+	for _, pattern := range []string{"lastException*.txt", "lastUIException*.txt"} {
+		matches, err := filepath.Glob(filepath.Join(docs, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return fileModTime(files[i]).After(fileModTime(files[j]))
+	})
+	return files, nil
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// ParseExceptionFile reads the reports out of one file. These aren't always
+// well formed (the game appends to them while running) so the decoder runs in
+// non-strict mode and we keep whatever reports we got before it gave up.
+func ParseExceptionFile(path string) ([]ExceptionReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	modTime := fileModTime(path)
+
+	decoder := xml.NewDecoder(file)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var reports []ExceptionReport
+	var current *ExceptionReport
+	var field string
+	var text strings.Builder
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF || len(reports) > 0 || current != nil {
+				break
+			}
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if name == "report" {
+				current = &ExceptionReport{File: path, Time: modTime}
+				continue
+			}
+			field = name
+			text.Reset()
+		case xml.CharData:
+			if field != "" {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if name == "report" && current != nil {
+				reports = append(reports, *current)
+				current = nil
+				continue
+			}
+			if current == nil || name != field {
+				continue
+			}
+			value := strings.TrimSpace(text.String())
+			switch field {
+			case "createtime":
+				current.CreateTime = value
+			case "buildsignature":
+				current.BuildSignature = value
+			case "categoryid":
+				current.Category = value
+			case "desyncdata":
+				current.Trace = value
+			}
+			field = ""
+		}
+	}
+
+	if current != nil && current.Trace != "" {
+		reports = append(reports, *current)
+	}
+
+	return reports, nil
+}
+
+func ParseTraceFrames(trace string) []TraceFrame {
+	var frames []TraceFrame
+	for _, m := range traceFramePattern.FindAllStringSubmatch(trace, -1) {
+		line, _ := strconv.Atoi(m[2])
+		frames = append(frames, TraceFrame{Path: m[1], Line: line, Func: m[3]})
+	}
+	return frames
+}
+
+// traceMessage is the last non-empty line of the trace, which for python is
+// the exception type and message.
+func traceMessage(trace string) string {
+	lines := strings.Split(strings.TrimSpace(trace), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line != "" && !strings.HasPrefix(line, "File \"") {
+			return line
+		}
+	}
+	return ""
+}
+
+// AttributeReport maps the frames of a trace to script archives and returns the
+// innermost frame that belongs to a mod.
+func AttributeReport(report ExceptionReport, index ScriptModuleIndex) ([]TraceFrame, *TraceFrame) {
+	frames := ParseTraceFrames(report.Trace)
+
+	var suspect *TraceFrame
+	for i := range frames {
+		archive, module, ok := index.Lookup(frames[i].Path)
+		if !ok {
+			continue
+		}
+		frames[i].Archive = archive
+		frames[i].Module = module
+		suspect = &frames[i]
+	}
+
+	return frames, suspect
+}
+
+// groupKey ignores line numbers so the same error from a mod that got a small
+// update still lands in one group.
+func groupKey(report ExceptionReport, frames []TraceFrame) string {
+	h := sha1.New()
+	io.WriteString(h, report.Category+"\n")
+	for _, frame := range frames {
+		io.WriteString(h, strings.ToLower(frame.Path)+":"+frame.Func+"\n")
+	}
+	io.WriteString(h, traceMessage(report.Trace))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GroupExceptions folds duplicate traces together, most frequent first.
+func GroupExceptions(reports []ExceptionReport, index ScriptModuleIndex) []ExceptionGroup {
+	groups := make(map[string]*ExceptionGroup)
+	var order []string
+
+	for _, report := range reports {
+		frames, suspect := AttributeReport(report, index)
+		key := groupKey(report, frames)
+
+		group, ok := groups[key]
+		if !ok {
+			group = &ExceptionGroup{
+				Key:      key,
+				Message:  traceMessage(report.Trace),
+				Category: report.Category,
+				Trace:    report.Trace,
+				Frames:   frames,
+			}
+			if suspect != nil {
+				group.Suspect = suspect.Archive
+				group.SuspectModule = suspect.Module
+			}
+			groups[key] = group
+			order = append(order, key)
+		}
+
+		group.Count++
+		if report.Time.After(group.LastSeen) {
+			group.LastSeen = report.Time
+		}
+		if len(group.Files) == 0 || group.Files[len(group.Files)-1] != report.File {
+			group.Files = append(group.Files, report.File)
+		}
+	}
+
+	result := make([]ExceptionGroup, 0, len(order))
+	for _, key := range order {
+		result = append(result, *groups[key])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+	return result
+}
+
+// AnalyzeExceptions parses every exception file in the documents folder and
+// attributes the errors to installed script mods.
+func AnalyzeExceptions(modsDir string) ([]ExceptionGroup, error) {
+	files, err := FindExceptionFiles(modsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := BuildScriptModuleIndex(modsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []ExceptionReport
+	for _, file := range files {
+		fileReports, err := ParseExceptionFile(file)
+		if err != nil {
+			continue
+		}
+		reports = append(reports, fileReports...)
+	}
+
+	return GroupExceptions(reports, index), nil
+}
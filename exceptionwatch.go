@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const exceptionSessionsFile = "exception_sessions.json"
+
+// the game rewrites an exception file several times while dumping a report,
+// so wait for it to go quiet before parsing
+const exceptionSettleDelay = 750 * time.Millisecond
+
+const maxExceptionSessions = 20
+
+type ExceptionEvent struct {
+	Time    time.Time `json:"time"`
+	File    string    `json:"file"`
+	Message string    `json:"message"`
+	Suspect string    `json:"suspect"`
+	Module  string    `json:"module"`
+	Trace   string    `json:"trace"`
+}
+
+type ExceptionSession struct {
+	Start  time.Time        `json:"start"`
+	Events []ExceptionEvent `json:"events"`
+}
+
+// ExceptionWatcher watches the documents folder for new exception reports and
+// keeps a timeline of them for the current session.
+type ExceptionWatcher struct {
+	modsDir string
+	watcher *fsnotify.Watcher
+	onEvent func(ExceptionEvent)
+
+	mu      sync.Mutex
+	seen    map[string]int
+	timers  map[string]*time.Timer
+	session ExceptionSession
+	done    chan struct{}
+
+	saveMu sync.Mutex
+}
+
+func isExceptionFile(path string) bool {
+	name := filepath.Base(path)
+	return filepath.Ext(name) == ".txt" &&
+		(strings.HasPrefix(name, "lastException") || strings.HasPrefix(name, "lastUIException"))
+}
+
+// StartExceptionWatcher starts watching. Reports that are already on disk are
+// treated as old news, only ones written after this call are reported.
+func StartExceptionWatcher(modsDir string, onEvent func(ExceptionEvent)) (*ExceptionWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := watcher.Add(documentsDir(modsDir)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w := &ExceptionWatcher{
+		modsDir: modsDir,
+		watcher: watcher,
+		onEvent: onEvent,
+		seen:    make(map[string]int),
+		timers:  make(map[string]*time.Timer),
+		session: ExceptionSession{Start: time.Now()},
+		done:    make(chan struct{}),
+	}
+
+	files, _ := FindExceptionFiles(modsDir)
+	for _, file := range files {
+		reports, _ := ParseExceptionFile(file)
+		w.seen[file] = len(reports)
+	}
+
+	go w.run()
+	return w, nil
+}
+
+func (w *ExceptionWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !isExceptionFile(event.Name) {
+				continue
+			}
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				// the game rotates old reports away, start counting from zero
+				w.mu.Lock()
+				delete(w.seen, event.Name)
+				w.mu.Unlock()
+				continue
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				w.schedule(event.Name)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("Exception watcher error: %v\n", err)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *ExceptionWatcher) schedule(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if timer, ok := w.timers[path]; ok {
+		timer.Reset(exceptionSettleDelay)
+		return
+	}
+	w.timers[path] = time.AfterFunc(exceptionSettleDelay, func() {
+		w.mu.Lock()
+		delete(w.timers, path)
+		w.mu.Unlock()
+		w.process(path)
+	})
+}
+
+func (w *ExceptionWatcher) process(path string) {
+	reports, err := ParseExceptionFile(path)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	start := w.seen[path]
+	if start > len(reports) {
+		// file was replaced with a shorter one
+		start = 0
+	}
+	w.seen[path] = len(reports)
+	w.mu.Unlock()
+
+	if start == len(reports) {
+		return
+	}
+
+	index, err := BuildScriptModuleIndex(w.modsDir)
+	if err != nil {
+		index = ScriptModuleIndex{}
+	}
+
+	for _, report := range reports[start:] {
+		_, suspect := AttributeReport(report, index)
+		event := ExceptionEvent{
+			Time:    time.Now(),
+			File:    path,
+			Message: traceMessage(report.Trace),
+			Trace:   report.Trace,
+		}
+		if suspect != nil {
+			event.Suspect = suspect.Archive
+			event.Module = suspect.Module
+		}
+
+		w.mu.Lock()
+		w.session.Events = append(w.session.Events, event)
+		session := w.session
+		w.mu.Unlock()
+
+		w.saveMu.Lock()
+		if err := saveExceptionSession(session); err != nil {
+			fmt.Printf("Failed to save exception timeline: %v\n", err)
+		}
+		w.saveMu.Unlock()
+		if w.onEvent != nil {
+			w.onEvent(event)
+		}
+	}
+}
+
+// Timeline returns the errors seen so far this session, oldest first.
+func (w *ExceptionWatcher) Timeline() []ExceptionEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]ExceptionEvent(nil), w.session.Events...)
+}
+
+// NewSession starts a fresh timeline, e.g. when the game is started again.
+func (w *ExceptionWatcher) NewSession() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.session = ExceptionSession{Start: time.Now()}
+}
+
+func (w *ExceptionWatcher) Stop() {
+	w.mu.Lock()
+	for _, timer := range w.timers {
+		timer.Stop()
+	}
+	w.mu.Unlock()
+
+	close(w.done)
+	w.watcher.Close()
+}
+
+func loadExceptionSessions() ([]ExceptionSession, error) {
+	var sessions []ExceptionSession
+
+	data, err := os.ReadFile(exceptionSessionsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sessions, nil
+		}
+		return sessions, err
+	}
+
+	err = json.Unmarshal(data, &sessions)
+	return sessions, err
+}
+
+// saveExceptionSession replaces the stored copy of this session (matched by
+// start time) and drops the oldest sessions past the limit.
+func saveExceptionSession(session ExceptionSession) error {
+	sessions, err := loadExceptionSessions()
+	if err != nil {
+		sessions = nil
+	}
+
+	replaced := false
+	for i := range sessions {
+		if sessions[i].Start.Equal(session.Start) {
+			sessions[i] = session
+			replaced = true
+		}
+	}
+	if !replaced {
+		sessions = append(sessions, session)
+	}
+	if len(sessions) > maxExceptionSessions {
+		sessions = sessions[len(sessions)-maxExceptionSessions:]
+	}
+
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(exceptionSessionsFile, data, 0644)
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spaolacci/murmur3"
 )
@@ -29,63 +33,59 @@ func CalculateFingerprint(path string) (uint, error) { // this murmur3 shit bett
 	return uint(binary.LittleEndian.Uint64(s)), nil
 }
 
-
-func CalculateFingerprintsForDir(dir string) ([]uint, error) { // fuck me this is gonna be slow
-	var fingerprints []uint
+// hashModFile reads a file once for both its fingerprint and its sha256.
+// The fingerprint is what CurseForge matches on, duplicates are decided by
+// the sha256 since files get deleted over it.
+func hashModFile(path string) (uint, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
 	
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		
-		ext := filepath.Ext(path)
-		if !info.IsDir() && (ext == ".package" || ext == ".ts4script") {
-			fp, err := CalculateFingerprint(path)
-			if err != nil {
-				return fmt.Errorf("error calculating fingerprint for %s: %v", path, err)
-			}
-			
-			fingerprints = append(fingerprints, fp)
-		}
-		
-		return nil
-	})
+	fingerprint := murmur3.New128()
+	sum := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(fingerprint, sum), file); err != nil {
+		return 0, "", err
+	}
 	
+	return uint(binary.LittleEndian.Uint64(fingerprint.Sum(nil))), hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+
+// CalculateFingerprintsForDir only hashes files the mod index hasn't seen,
+// disabled files are left out since the game doesn't load them.
+func CalculateFingerprintsForDir(dir string) ([]uint, error) {
+	files, err := IndexedFingerprints(dir)
 	if err != nil {
 		return nil, err
 	}
 	
+	var fingerprints []uint
+	for _, file := range files {
+		if !strings.HasSuffix(file.Path, disabledSuffix) {
+			fingerprints = append(fingerprints, file.Fingerprint)
+		}
+	}
 	return fingerprints, nil
 }
 
 
 func CalculateFuzzyFingerprintsForDir(dir string) ([]FolderFingerprint, error) { // why the fuck do we need fuzzy matching
-	folders := make(map[string][]uint)
-	
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		
-		ext := filepath.Ext(path)
-		if !info.IsDir() && (ext == ".package" || ext == ".ts4script") {
-			folderName := filepath.Base(filepath.Dir(path))
-			
-			fp, err := CalculateFingerprint(path)
-			if err != nil {
-				return fmt.Errorf("error calculating fingerprint for %s: %v", path, err)
-			}
-			
-			folders[folderName] = append(folders[folderName], fp)
-		}
-		
-		return nil
-	})
-	
+	files, err := IndexedFingerprints(dir)
 	if err != nil {
 		return nil, err
 	}
 	
+	folders := make(map[string][]uint)
+	for _, file := range files {
+		if strings.HasSuffix(file.Path, disabledSuffix) {
+			continue
+		}
+		folderName := filepath.Base(filepath.Dir(file.Path))
+		folders[folderName] = append(folders[folderName], file.Fingerprint)
+	}
+	
 	var result []FolderFingerprint
 	for folder, prints := range folders {
 		result = append(result, FolderFingerprint{
@@ -120,4 +120,18 @@ func (c *ApiClient) MatchFuzzyFingerprints(fingerprints []FolderFingerprint) (Fi
 	
 	err = json.Unmarshal(responseBody, &result)
 	return result, err
-}
\ No newline at end of file
+}
+
+// FileFingerprint is one file from the fingerprint walk, kept with its path so
+// files can be compared with each other and not just looked up on CurseForge.
+type FileFingerprint struct {
+	Path        string
+	Fingerprint uint
+	SHA256      string
+	Size        int64
+	ModTime     time.Time
+}
+
+func CalculateFileFingerprintsForDir(dir string) ([]FileFingerprint, error) {
+	return IndexedFingerprints(dir)
+}
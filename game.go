@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var gameVersionPattern = regexp.MustCompile(`\d+\.\d+\.\d+\.\d+`)
+
+// the game keeps its user files (Mods, Options.ini, caches, exception logs)
+// in the folder that contains the Mods directory
+func documentsDir(modsDir string) string {
+	return filepath.Dir(modsDir)
+}
+
+// DetectGameVersion reads GameVersion.txt, which the game rewrites on every
+// launch after a patch. The file has a few junk bytes in front of the version
+// string so we just pull the first dotted version out of it. The returned time
+// is when the game last wrote the file, which is the closest thing we have to
+// a patch date.
+func DetectGameVersion(modsDir string) (string, time.Time, error) {
+	path := filepath.Join(documentsDir(modsDir), "GameVersion.txt")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	version := gameVersionPattern.FindString(string(data))
+	if version == "" {
+		return "", time.Time{}, fmt.Errorf("no version found in %s", path)
+	}
+
+	return version, info.ModTime(), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errGameRunning = errors.New("The Sims 4 is running, close the game first")
+
+// lowercase, wine keeps the windows name in the command line
+var gameExecutables = []string{"ts4_x64.exe", "ts4_dx9_x64.exe", "ts4.exe"}
+
+var (
+	gameStateMu        sync.Mutex
+	gameStateListeners []func(running bool)
+)
+
+// FindGameProcess looks through /proc for the game running under Proton or
+// wine. The exe shows up in the command line of the wine process (as a
+// windows path) and usually in comm as well.
+func FindGameProcess() (int, bool) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, false
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		if comm, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "comm")); err == nil {
+			if isGameExecutable(strings.TrimSpace(string(comm))) {
+				return pid, true
+			}
+		}
+
+		cmdline, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		for _, arg := range bytes.Split(cmdline, []byte{0}) {
+			if isGameExecutable(string(arg)) {
+				return pid, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func isGameExecutable(arg string) bool {
+	arg = strings.ReplaceAll(arg, `\`, "/")
+	name := strings.ToLower(filepath.Base(arg))
+	for _, exe := range gameExecutables {
+		if name == exe {
+			return true
+		}
+	}
+	return false
+}
+
+func IsGameRunning() bool {
+	_, running := FindGameProcess()
+	return running
+}
+
+// onGameStateChange registers a callback for when the game starts or exits.
+// Callbacks run on the monitor goroutine.
+func onGameStateChange(fn func(running bool)) {
+	gameStateMu.Lock()
+	defer gameStateMu.Unlock()
+	gameStateListeners = append(gameStateListeners, fn)
+}
+
+// monitorGameProcess polls for the game. When it exits the queued Mods folder
+// changes are applied before anyone else hears about it.
+func monitorGameProcess(interval time.Duration) {
+	running := IsGameRunning()
+
+	for range time.Tick(interval) {
+		now := IsGameRunning()
+		if now == running {
+			continue
+		}
+		running = now
+
+		if !running {
+			applyQueuedChanges()
+		}
+
+		gameStateMu.Lock()
+		listeners := append([]func(bool){}, gameStateListeners...)
+		gameStateMu.Unlock()
+
+		for _, fn := range listeners {
+			fn(running)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mod_index.dat remembers what the manager found out about every mod file:
+// the resource keys and metadata of packages, the fingerprint and the sha256
+// duplicates are found by. An entry is kept as long as the file's
+// size, modification time and inode don't change, so a refresh only reads the
+// files that did. Moved and renamed files keep their entry.
+const modIndexFile = "mod_index.dat"
+
+type IndexEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Inode   uint64    `json:"inode,omitempty"`
+	// zero until something needs it, hashing everything up front is slow
+	Fingerprint uint          `json:"fingerprint,omitempty"`
+	SHA256      string        `json:"sha256,omitempty"`
+	Keys        []ResourceKey `json:"keys,omitempty"`
+	Info        *PackageInfo  `json:"info,omitempty"`
+	// set when the file isn't a package we can read
+	Error string `json:"error,omitempty"`
+}
+
+// current is true when the entry still describes the file. A zero inode means
+// it's unknown, which happens off unix.
+func (e IndexEntry) current(mod ModInfo) bool {
+	return e.Size == mod.FileSize && e.ModTime.Equal(mod.InstallDate) &&
+		(e.Inode == 0 || mod.Inode == 0 || e.Inode == mod.Inode)
+}
+
+type ModIndex struct {
+	Files map[string]IndexEntry `json:"files"`
+}
+
+var (
+	modIndexMu sync.Mutex
+	modIndex   *ModIndex
+	// instance to the files that have it, rebuilt after the index changes
+	modIndexByInstance map[uint64][]string
+)
+
+func loadModIndexLocked() *ModIndex {
+	if modIndex != nil {
+		return modIndex
+	}
+
+	index := &ModIndex{}
+	if err := loadCompressedJson(index, modIndexFile); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Failed to load the mod index, rebuilding it: %v\n", err)
+		index = &ModIndex{}
+	}
+	if index.Files == nil {
+		index.Files = make(map[string]IndexEntry)
+	}
+	modIndex = index
+	modIndexByInstance = nil
+	return index
+}
+
+// indexModFile reads everything the index keeps about a file except the
+// fingerprint.
+func indexModFile(mod ModInfo) IndexEntry {
+	entry := IndexEntry{Size: mod.FileSize, ModTime: mod.InstallDate, Inode: mod.Inode}
+	if modExtension(mod.FilePath) != ".package" {
+		entry.Info = &PackageInfo{Category: CategoryScript}
+		return entry
+	}
+
+	pkg, err := ReadDBPF(mod.FilePath)
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Info = &PackageInfo{Category: CategoryUnknown}
+		return entry
+	}
+	entry.Keys = pkg.Keys()
+	info := analyzeDBPF(pkg)
+	entry.Info = &info
+	return entry
+}
+
+// UpdateModIndex brings the index in line with a scan of modsDir. Only new and
+// changed files are read, it returns how many that were.
+func UpdateModIndex(modsDir string, mods []ModInfo) (int, error) {
+	return UpdateModIndexContext(context.Background(), modsDir, mods, nil)
+}
+
+// UpdateModIndexContext reads the changed files on the worker pool. When it's
+// cancelled what was read so far is still saved.
+func UpdateModIndexContext(ctx context.Context, modsDir string, mods []ModInfo, progress ProgressFunc) (int, error) {
+	modIndexMu.Lock()
+	index := loadModIndexLocked()
+
+	seen := make(map[string]bool)
+	for _, mod := range mods {
+		seen[mod.FilePath] = true
+	}
+
+	// entries of files that aren't where they were, to find them again after a
+	// rename or a move into another folder
+	type identity struct {
+		inode   uint64
+		size    int64
+		modTime int64
+	}
+	moved := make(map[identity]IndexEntry)
+	for path, entry := range index.Files {
+		if !seen[path] && entry.Inode != 0 {
+			moved[identity{entry.Inode, entry.Size, entry.ModTime.UnixNano()}] = entry
+		}
+	}
+
+	changed := 0
+	var toRead []ModInfo
+	for _, mod := range mods {
+		if entry, ok := index.Files[mod.FilePath]; ok && entry.current(mod) {
+			continue
+		}
+		changed++
+		if entry, ok := moved[identity{mod.Inode, mod.FileSize, mod.InstallDate.UnixNano()}]; ok && mod.Inode != 0 {
+			index.Files[mod.FilePath] = entry
+			continue
+		}
+		toRead = append(toRead, mod)
+	}
+
+	// whatever was in modsDir and isn't anymore is gone
+	prefix := filepath.Clean(modsDir) + string(filepath.Separator)
+	for path := range index.Files {
+		if strings.HasPrefix(path, prefix) && !seen[path] {
+			delete(index.Files, path)
+			changed++
+		}
+	}
+	modIndexMu.Unlock()
+
+	// reading happens without the lock, lookups keep working meanwhile
+	entries := make([]IndexEntry, len(toRead))
+	read := make([]bool, len(toRead))
+	reporter := newProgressReporter(progress, "Reading packages", len(toRead))
+	poolErr := runPool(ctx, len(toRead), func(i int) {
+		entries[i] = indexModFile(toRead[i])
+		read[i] = true
+	}, reporter)
+
+	modIndexMu.Lock()
+	defer modIndexMu.Unlock()
+
+	count := 0
+	for i, mod := range toRead {
+		if read[i] {
+			index.Files[mod.FilePath] = entries[i]
+			count++
+		}
+	}
+
+	if changed == 0 {
+		return 0, poolErr
+	}
+	modIndexByInstance = nil
+	if err := saveCompressedJson(index, modIndexFile); err != nil {
+		return count, err
+	}
+	return count, poolErr
+}
+
+// IndexedFingerprints returns the fingerprint and sha256 of every mod file in
+// modsDir, hashing only the files the index doesn't have them for yet.
+func IndexedFingerprints(modsDir string) ([]FileFingerprint, error) {
+	return IndexedFingerprintsContext(context.Background(), modsDir, nil)
+}
+
+func IndexedFingerprintsContext(ctx context.Context, modsDir string, progress ProgressFunc) ([]FileFingerprint, error) {
+	mods, err := scanModsContext(ctx, modsDir, progress)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := UpdateModIndexContext(ctx, modsDir, mods, progress); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		fmt.Printf("Failed to save the mod index: %v\n", err)
+	}
+
+	modIndexMu.Lock()
+	index := loadModIndexLocked()
+	var toHash []ModInfo
+	for _, mod := range mods {
+		// both come from one read, entries from before the sha256 get hashed again
+		if index.Files[mod.FilePath].SHA256 == "" {
+			toHash = append(toHash, mod)
+		}
+	}
+	modIndexMu.Unlock()
+
+	fingerprints := make([]uint, len(toHash))
+	sums := make([]string, len(toHash))
+	errs := make([]error, len(toHash))
+	reporter := newProgressReporter(progress, "Hashing files", len(toHash))
+	poolErr := runPool(ctx, len(toHash), func(i int) {
+		fingerprints[i], sums[i], errs[i] = hashModFile(toHash[i].FilePath)
+	}, reporter)
+
+	modIndexMu.Lock()
+	defer modIndexMu.Unlock()
+
+	var hashErr error
+	hashed := 0
+	for i, mod := range toHash {
+		if errs[i] != nil {
+			if hashErr == nil {
+				hashErr = fmt.Errorf("error calculating fingerprint for %s: %v", mod.FilePath, errs[i])
+			}
+			continue
+		}
+		// the file may have been indexed again while we were hashing
+		if entry, ok := index.Files[mod.FilePath]; ok && entry.current(mod) {
+			entry.Fingerprint = fingerprints[i]
+			entry.SHA256 = sums[i]
+			index.Files[mod.FilePath] = entry
+			hashed++
+		}
+	}
+	if hashed > 0 {
+		if err := saveCompressedJson(index, modIndexFile); err != nil {
+			fmt.Printf("Failed to save the mod index: %v\n", err)
+		}
+	}
+	if poolErr != nil {
+		return nil, poolErr
+	}
+	if hashErr != nil {
+		return nil, hashErr
+	}
+
+	files := make([]FileFingerprint, 0, len(mods))
+	for _, mod := range mods {
+		entry := index.Files[mod.FilePath]
+		files = append(files, FileFingerprint{Path: mod.FilePath, Fingerprint: entry.Fingerprint, SHA256: entry.SHA256, Size: mod.FileSize, ModTime: mod.InstallDate})
+	}
+	return files, nil
+}
+
+// indexedMod returns the index entry for a file if it's up to date.
+func indexedMod(mod ModInfo) (IndexEntry, bool) {
+	modIndexMu.Lock()
+	defer modIndexMu.Unlock()
+
+	entry, ok := loadModIndexLocked().Files[mod.FilePath]
+	if !ok || !entry.current(mod) {
+		return IndexEntry{}, false
+	}
+	return entry, true
+}
+
+// indexedKeys returns the resource keys the index has for a path. Callers
+// update the index first.
+func indexedKeys(path string) []ResourceKey {
+	modIndexMu.Lock()
+	defer modIndexMu.Unlock()
+	return loadModIndexLocked().Files[path].Keys
+}
+
+// ResourceQuery is what to look for: a full key, or just an instance that
+// could be written in more than one way.
+type ResourceQuery struct {
+	Instances []uint64
+	// zero when only the instance was given
+	Type  uint32
+	Group uint32
+	Full  bool
+}
+
+// ParseResourceQuery accepts a type:group:instance key or a bare instance.
+// Instances are hex with 0x or hex letters, tuning XML writes them in decimal,
+// plain digits could be either so both get tried.
+func ParseResourceQuery(text string) (ResourceQuery, error) {
+	text = strings.TrimSpace(text)
+	if strings.ContainsAny(text, ":-") {
+		key, err := ParseResourceKey(text)
+		if err != nil {
+			return ResourceQuery{}, err
+		}
+		return ResourceQuery{Instances: []uint64{key.Instance}, Type: key.Type, Group: key.Group, Full: true}, nil
+	}
+
+	lower := strings.ToLower(text)
+	if strings.HasPrefix(lower, "0x") {
+		instance, err := strconv.ParseUint(lower[2:], 16, 64)
+		if err != nil {
+			return ResourceQuery{}, fmt.Errorf("bad instance %q", text)
+		}
+		return ResourceQuery{Instances: []uint64{instance}}, nil
+	}
+
+	var query ResourceQuery
+	if instance, err := strconv.ParseUint(lower, 10, 64); err == nil {
+		query.Instances = append(query.Instances, instance)
+	}
+	if instance, err := strconv.ParseUint(lower, 16, 64); err == nil {
+		if len(query.Instances) == 0 || query.Instances[0] != instance {
+			query.Instances = append(query.Instances, instance)
+		}
+	}
+	if len(query.Instances) == 0 {
+		return query, fmt.Errorf("bad instance %q, expected hex, decimal or type:group:instance", text)
+	}
+	return query, nil
+}
+
+type ResourceMatch struct {
+	Path string
+	Key  ResourceKey
+}
+
+// LookupResource finds the packages in modsDir that have a resource. The index
+// is brought up to date first, which only reads what changed since the last
+// scan.
+func LookupResource(modsDir, text string) ([]ResourceMatch, error) {
+	query, err := ParseResourceQuery(text)
+	if err != nil {
+		return nil, err
+	}
+
+	mods, err := scanMods(modsDir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := UpdateModIndex(modsDir, mods); err != nil {
+		fmt.Printf("Failed to save the mod index: %v\n", err)
+	}
+
+	modIndexMu.Lock()
+	defer modIndexMu.Unlock()
+
+	index := loadModIndexLocked()
+	if modIndexByInstance == nil {
+		modIndexByInstance = make(map[uint64][]string)
+		for path, entry := range index.Files {
+			for _, key := range entry.Keys {
+				modIndexByInstance[key.Instance] = append(modIndexByInstance[key.Instance], path)
+			}
+		}
+	}
+
+	prefix := filepath.Clean(modsDir) + string(filepath.Separator)
+	seen := make(map[ResourceMatch]bool)
+	var matches []ResourceMatch
+	for _, instance := range query.Instances {
+		for _, path := range modIndexByInstance[instance] {
+			if !strings.HasPrefix(path, prefix) {
+				continue
+			}
+			for _, key := range index.Files[path].Keys {
+				if key.Instance != instance || (query.Full && (key.Type != query.Type || key.Group != query.Group)) {
+					continue
+				}
+				match := ResourceMatch{Path: path, Key: key}
+				if !seen[match] {
+					seen[match] = true
+					matches = append(matches, match)
+				}
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Path != matches[j].Path {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].Key.String() < matches[j].Key.String()
+	})
+	return matches, nil
+}
+
+func formatResourceMatches(modsDir, text string, matches []ResourceMatch) string {
+	if len(matches) == 0 {
+		return "No installed package has " + strings.TrimSpace(text) + ".\n"
+	}
+
+	var b strings.Builder
+	for _, match := range matches {
+		rel, err := filepath.Rel(modsDir, match.Path)
+		if err != nil {
+			rel = match.Path
+		}
+		fmt.Fprintf(&b, "%s  %s (%s)\n", rel, match.Key, resourceTypeName(match.Key.Type))
+	}
+	return b.String()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// no inodes here, size and modification time have to do
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
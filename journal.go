@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// every change to the Mods folder is appended here, one JSON object per line.
+// Nothing is ever rewritten, undoing appends an undo entry.
+const journalFile = "journal.jsonl"
+
+const (
+	JournalInstall   = "install"
+	JournalOverwrite = "overwrite"
+	JournalMove      = "move"
+	JournalDelete    = "delete"
+	JournalUndo      = "undo"
+)
+
+type JournalEntry struct {
+	ID   int64     `json:"id"`
+	Time time.Time `json:"time"`
+	Op   string    `json:"op"`
+	// the file the operation left behind (or removed, for delete)
+	Path string `json:"path,omitempty"`
+	// move: where the file was before
+	From string `json:"from,omitempty"`
+	// overwrite and delete: the old file in the trash
+	Backup string `json:"backup,omitempty"`
+	// undo: the entry that was undone
+	Ref int64 `json:"ref,omitempty"`
+	// entries done as one action, a mod and its translations, share a group
+	// and are undone together
+	Group int64 `json:"group,omitempty"`
+}
+
+func (e JournalEntry) Description() string {
+	switch e.Op {
+	case JournalInstall:
+		return "install " + filepath.Base(e.Path)
+	case JournalOverwrite:
+		return "overwrite " + filepath.Base(e.Path)
+	case JournalMove:
+		return fmt.Sprintf("move %s to %s", filepath.Base(e.From), filepath.Base(e.Path))
+	case JournalDelete:
+		return "delete " + filepath.Base(e.Path)
+	}
+	return e.Op
+}
+
+// describeOperation names a group of entries by the first thing it did.
+func describeOperation(group []JournalEntry) string {
+	if len(group) == 0 {
+		return ""
+	}
+	description := group[0].Description()
+	switch len(group) {
+	case 1:
+	case 2:
+		description += " and 1 more file"
+	default:
+		description += fmt.Sprintf(" and %d more files", len(group)-1)
+	}
+	return description
+}
+
+var journalMu sync.Mutex
+
+// newJournalGroup starts a group for operations that belong together.
+func newJournalGroup() int64 {
+	return time.Now().UnixNano()
+}
+
+func loadJournal() ([]JournalEntry, error) {
+	file, err := os.Open(journalFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry JournalEntry
+		// a line cut off by a crash shouldn't lose the rest
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func appendJournalLocked(entry JournalEntry) error {
+	entry.Time = time.Now()
+	if entry.ID == 0 {
+		entry.ID = entry.Time.UnixNano()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(journalFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// logFileOp records an operation that already happened. A failure to log is
+// printed, not returned, the file operation itself went through.
+func logFileOp(entry JournalEntry) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	if err := appendJournalLocked(entry); err != nil {
+		fmt.Printf("Failed to write journal: %v\n", err)
+	}
+}
+
+// journaledInstall moves a new file into place. Whatever was at target goes to
+// the trash first so the overwrite can be undone.
+func journaledInstall(source, target string) error {
+	backup := ""
+	if _, err := os.Lstat(target); err == nil {
+		trashed, err := TrashFile(target)
+		if err != nil {
+			return fmt.Errorf("failed to move the old %s to the trash: %w", filepath.Base(target), err)
+		}
+		backup = trashed
+	}
+
+	if err := moveFile(source, target); err != nil {
+		if backup != "" {
+			RestoreFromTrash(backup, target)
+		}
+		return err
+	}
+
+	if backup != "" {
+		logFileOp(JournalEntry{Op: JournalOverwrite, Path: target, Backup: backup})
+	} else {
+		logFileOp(JournalEntry{Op: JournalInstall, Path: target})
+	}
+	return nil
+}
+
+// journaledMove and journaledDelete take the group the operation is part of,
+// 0 when it stands alone.
+func journaledMove(group int64, from, to string) error {
+	if err := moveFile(from, to); err != nil {
+		return err
+	}
+	logFileOp(JournalEntry{Op: JournalMove, From: from, Path: to, Group: group})
+	return nil
+}
+
+// journaledDelete sends path to the trash instead of deleting it.
+func journaledDelete(group int64, path string) error {
+	trashed, err := TrashFile(path)
+	if err != nil {
+		return err
+	}
+	logFileOp(JournalEntry{Op: JournalDelete, Path: path, Backup: trashed, Group: group})
+	return nil
+}
+
+// lastUndoable finds the newest operation nobody has undone yet, with the
+// rest of its group, oldest first. Undos themselves aren't undoable, undoing
+// again walks further back instead.
+func lastUndoable(entries []JournalEntry) ([]JournalEntry, bool) {
+	undone := make(map[int64]bool)
+	for _, entry := range entries {
+		if entry.Op == JournalUndo {
+			undone[entry.Ref] = true
+		}
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		last := entries[i]
+		if last.Op == JournalUndo || undone[last.ID] {
+			continue
+		}
+		if last.Group == 0 {
+			return []JournalEntry{last}, true
+		}
+		var group []JournalEntry
+		for _, entry := range entries[:i+1] {
+			if entry.Group == last.Group && entry.Op != JournalUndo && !undone[entry.ID] {
+				group = append(group, entry)
+			}
+		}
+		return group, true
+	}
+	return nil, false
+}
+
+// LastOperation is what UndoLastOperation would undo.
+func LastOperation() ([]JournalEntry, bool, error) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	entries, err := loadJournal()
+	if err != nil {
+		return nil, false, err
+	}
+	group, ok := lastUndoable(entries)
+	return group, ok, nil
+}
+
+func undoEntry(entry JournalEntry) error {
+	switch entry.Op {
+	case JournalInstall:
+		_, err := TrashFile(entry.Path)
+		return err
+	case JournalOverwrite:
+		if _, err := TrashFile(entry.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return RestoreFromTrash(entry.Backup, entry.Path)
+	case JournalMove:
+		if _, err := os.Lstat(entry.From); err == nil {
+			return fmt.Errorf("can't move %s back, %s exists again", filepath.Base(entry.Path), entry.From)
+		}
+		return moveFile(entry.Path, entry.From)
+	case JournalDelete:
+		return RestoreFromTrash(entry.Backup, entry.Path)
+	}
+	return fmt.Errorf("don't know how to undo %q", entry.Op)
+}
+
+// UndoLastOperation replays the inverse of the newest operation in the journal,
+// newest step first, and records each step it undid.
+func UndoLastOperation() ([]JournalEntry, error) {
+	if IsGameRunning() {
+		return nil, errGameRunning
+	}
+
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	entries, err := loadJournal()
+	if err != nil {
+		return nil, err
+	}
+	group, ok := lastUndoable(entries)
+	if !ok {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+
+	for i := len(group) - 1; i >= 0; i-- {
+		entry := group[i]
+		if err := undoEntry(entry); err != nil {
+			onModsChanged()
+			return group, fmt.Errorf("failed to undo %s: %w", entry.Description(), err)
+		}
+		if err := appendJournalLocked(JournalEntry{Op: JournalUndo, Ref: entry.ID}); err != nil {
+			return group, err
+		}
+	}
+
+	onModsChanged()
+	return group, nil
+}
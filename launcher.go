@@ -0,0 +1,351 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const defaultLaunchCommand = "steam steam://rungameid/" + sims4SteamAppID
+
+const (
+	SeverityOK = iota
+	SeverityWarning
+	SeverityBlocker
+)
+
+type CheckResult struct {
+	Name     string
+	Severity int
+	Messages []string
+}
+
+// LaunchCheck is one step of the pre-launch pipeline. Anything can add one with
+// registerLaunchCheck, they run in the order they were registered.
+type LaunchCheck struct {
+	Name string
+	Run  func(settings AppSettings) CheckResult
+}
+
+var (
+	launchChecksMu sync.Mutex
+	launchChecks   []LaunchCheck
+)
+
+func registerLaunchCheck(check LaunchCheck) {
+	launchChecksMu.Lock()
+	defer launchChecksMu.Unlock()
+	launchChecks = append(launchChecks, check)
+}
+
+func init() {
+	registerLaunchCheck(LaunchCheck{Name: "Game not already running", Run: checkGameNotRunning})
+	registerLaunchCheck(LaunchCheck{Name: "Options.ini flags", Run: checkOptionsIniFlags})
+	registerLaunchCheck(LaunchCheck{Name: "Misplaced files", Run: checkMisplacedFiles})
+	registerLaunchCheck(LaunchCheck{Name: "Conflicting files", Run: checkConflictingFiles})
+	registerLaunchCheck(LaunchCheck{Name: "Script mods", Run: checkScriptArchives})
+	registerLaunchCheck(LaunchCheck{Name: "Suspicious scripts", Run: checkSuspiciousScripts})
+	registerLaunchCheck(LaunchCheck{Name: "Patch compatibility", Run: checkPatchCompatibility})
+	registerLaunchCheck(LaunchCheck{Name: "Missing dependencies", Run: checkMissingDependencies})
+}
+
+// RunLaunchChecks runs every registered check and returns the results plus the
+// worst severity seen.
+func RunLaunchChecks(settings AppSettings) ([]CheckResult, int) {
+	launchChecksMu.Lock()
+	checks := append([]LaunchCheck(nil), launchChecks...)
+	launchChecksMu.Unlock()
+
+	worst := SeverityOK
+	var results []CheckResult
+	for _, check := range checks {
+		result := check.Run(settings)
+		result.Name = check.Name
+		if result.Severity > worst {
+			worst = result.Severity
+		}
+		results = append(results, result)
+	}
+	return results, worst
+}
+
+func severityName(severity int) string {
+	switch severity {
+	case SeverityBlocker:
+		return "BLOCKER"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "ok"
+}
+
+func formatCheckResults(results []CheckResult) string {
+	var b strings.Builder
+	for _, result := range results {
+		fmt.Fprintf(&b, "[%s] %s\n", severityName(result.Severity), result.Name)
+		for _, message := range result.Messages {
+			fmt.Fprintf(&b, "    %s\n", message)
+		}
+	}
+	return b.String()
+}
+
+// LaunchGame runs the configured launch command through the shell so people can
+// use whatever their launcher wants (steam URIs, lutris, heroic, ...).
+func LaunchGame(settings AppSettings) error {
+	command := settings.LaunchCommand
+	if command == "" {
+		command = defaultLaunchCommand
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run %q: %w", command, err)
+	}
+
+	go cmd.Wait()
+	return nil
+}
+
+func checkGameNotRunning(settings AppSettings) CheckResult {
+	if IsGameRunning() {
+		return CheckResult{Severity: SeverityBlocker, Messages: []string{"The Sims 4 is already running"}}
+	}
+	return CheckResult{}
+}
+
+func checkOptionsIniFlags(settings AppSettings) CheckResult {
+	ini, err := LoadOptionsIni(settings.ModsDirectory)
+	if err != nil {
+		return CheckResult{Severity: SeverityWarning, Messages: []string{"can't read Options.ini: " + err.Error()}}
+	}
+
+	var result CheckResult
+	if !ini.ModsEnabled() {
+		result.Severity = SeverityWarning
+		result.Messages = append(result.Messages, "custom content and mods are switched off in Options.ini")
+	} else if !ini.ScriptModsEnabled() {
+		archives, _ := filepath.Glob(filepath.Join(settings.ModsDirectory, "*.ts4script"))
+		nested, _ := filepath.Glob(filepath.Join(settings.ModsDirectory, "*", "*.ts4script"))
+		if len(archives)+len(nested) > 0 {
+			result.Severity = SeverityWarning
+			result.Messages = append(result.Messages, "script mods are switched off but script mods are installed")
+		}
+	}
+	return result
+}
+
+// checkMisplacedFiles catches the two placement mistakes the game is silent
+// about: scripts more than one folder deep and packages deeper than
+// Resource.cfg allows.
+func checkMisplacedFiles(settings AppSettings) CheckResult {
+	var result CheckResult
+
+	filepath.Walk(settings.ModsDirectory, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if filepath.Ext(path) == ".ts4script" && packageDepth(settings.ModsDirectory, path) > 1 {
+			result.Messages = append(result.Messages, "script too deep to load: "+path)
+		}
+		return nil
+	})
+
+	if cfg, err := ParseResourceCfg(settings.ModsDirectory); err == nil {
+		unreachable, _ := UnreachablePackages(settings.ModsDirectory, cfg)
+		for _, path := range unreachable {
+			result.Messages = append(result.Messages, "package deeper than Resource.cfg allows: "+path)
+		}
+	}
+
+	if len(result.Messages) > 0 {
+		result.Severity = SeverityWarning
+	}
+	return result
+}
+
+// checkConflictingFiles flags the same mod file installed more than once.
+// The game loads both and which one wins is down to load order.
+func checkConflictingFiles(settings AppSettings) CheckResult {
+	var result CheckResult
+
+	mods, err := scanMods(settings.ModsDirectory)
+	if err != nil {
+		return CheckResult{Severity: SeverityWarning, Messages: []string{err.Error()}}
+	}
+
+	byName := make(map[string][]string)
+	for _, mod := range mods {
+		if mod.Disabled {
+			continue
+		}
+		key := strings.ToLower(mod.Name)
+		byName[key] = append(byName[key], mod.FilePath)
+	}
+
+	var names []string
+	for name, paths := range byName {
+		if len(paths) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		result.Messages = append(result.Messages, fmt.Sprintf("%s is installed %d times: %s", name, len(byName[name]), strings.Join(byName[name], ", ")))
+	}
+	if len(result.Messages) > 0 {
+		result.Severity = SeverityWarning
+	}
+	return result
+}
+
+// checkScriptArchives looks inside the .ts4script files for bytecode the game
+// can't import and modules two mods both ship.
+func checkScriptArchives(settings AppSettings) CheckResult {
+	archives, err := ScanScriptArchives(settings.ModsDirectory)
+	if err != nil {
+		return CheckResult{Severity: SeverityWarning, Messages: []string{err.Error()}}
+	}
+
+	var result CheckResult
+	for _, archive := range archives {
+		name := filepath.Base(archive.Path)
+		for _, problem := range archive.BadBytecode {
+			result.Messages = append(result.Messages, name+": "+problem)
+		}
+		if len(archive.SourceOnly) > 0 {
+			result.Messages = append(result.Messages, fmt.Sprintf("%s: %d modules have no bytecode", name, len(archive.SourceOnly)))
+		}
+	}
+	for _, conflict := range FindScriptModuleConflicts(archives) {
+		result.Messages = append(result.Messages, fmt.Sprintf("module %s is shipped by %s", conflict.Module, strings.Join(conflict.Archives, ", ")))
+	}
+
+	if len(result.Messages) > 0 {
+		result.Severity = SeverityWarning
+	}
+	return result
+}
+
+func checkSuspiciousScripts(settings AppSettings) CheckResult {
+	flagged, err := ScanInstalledScripts(settings.ModsDirectory)
+	if err != nil {
+		return CheckResult{Severity: SeverityWarning, Messages: []string{err.Error()}}
+	}
+
+	var result CheckResult
+	for path, findings := range flagged {
+		result.Messages = append(result.Messages, fmt.Sprintf("%s %s, quarantine it from the Diagnostics tab if you don't trust it", filepath.Base(path), summarizeFindings(findings)))
+	}
+	sort.Strings(result.Messages)
+
+	if len(result.Messages) > 0 {
+		result.Severity = SeverityWarning
+	}
+	return result
+}
+
+// checkPatchCompatibility warns about script mods that are still enabled even
+// though they predate the patch patch day is tracking.
+func checkPatchCompatibility(settings AppSettings) CheckResult {
+	state, err := loadPatchDay()
+	if err != nil || !state.Active {
+		return CheckResult{}
+	}
+
+	mods, err := scanMods(settings.ModsDirectory)
+	if err != nil {
+		return CheckResult{Severity: SeverityWarning, Messages: []string{err.Error()}}
+	}
+
+	var result CheckResult
+	for _, mod := range mods {
+		if mod.Disabled || modExtension(mod.FilePath) != ".ts4script" {
+			continue
+		}
+		if mod.InstallDate.Before(state.PatchDate) {
+			result.Messages = append(result.Messages, fmt.Sprintf("%s predates the %s patch", mod.Name, state.GameVersion))
+		}
+	}
+	for _, entry := range state.Entries {
+		if entry.Status == PatchStatusUpdateAvailable {
+			result.Messages = append(result.Messages, entry.Name()+" has an update for the new patch that isn't installed")
+		}
+	}
+
+	if len(result.Messages) > 0 {
+		result.Severity = SeverityWarning
+	}
+	return result
+}
+
+// checkMissingDependencies matches the installed files against CurseForge and
+// looks at the required dependencies of each match. Without an API key there's
+// nothing to look up, so it's skipped.
+func checkMissingDependencies(settings AppSettings) CheckResult {
+	if apiClient == nil {
+		return CheckResult{Messages: []string{"skipped, no CurseForge API key"}}
+	}
+
+	fingerprints, err := CalculateFingerprintsForDir(settings.ModsDirectory)
+	if err != nil {
+		return CheckResult{Severity: SeverityWarning, Messages: []string{err.Error()}}
+	}
+	if len(fingerprints) == 0 {
+		return CheckResult{}
+	}
+
+	resp, err := apiClient.MatchFingerprints(fingerprints)
+	if err != nil {
+		return CheckResult{Severity: SeverityWarning, Messages: []string{"couldn't reach CurseForge: " + err.Error()}}
+	}
+
+	installed := make(map[int]bool)
+	for _, match := range resp.Data.ExactMatches {
+		installed[match.ID] = true
+	}
+
+	missing := make(map[int][]string)
+	for _, match := range resp.Data.ExactMatches {
+		for _, dep := range match.File.Dependencies {
+			if dep.RelationType == RelationTypeRequired && !installed[dep.ModID] {
+				missing[dep.ModID] = append(missing[dep.ModID], match.File.DisplayName)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return CheckResult{}
+	}
+
+	var ids []int
+	for id := range missing {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	names := make(map[int]string)
+	if modsResp, err := apiClient.GetModsByIds(ids); err == nil {
+		for _, mod := range modsResp.Data {
+			names[mod.ID] = mod.Name
+		}
+	}
+
+	var result CheckResult
+	result.Severity = SeverityWarning
+	for _, id := range ids {
+		name := names[id]
+		if name == "" {
+			name = fmt.Sprintf("mod %d", id)
+		}
+		result.Messages = append(result.Messages, fmt.Sprintf("%s is required by %s", name, strings.Join(missing[id], ", ")))
+	}
+	return result
+}
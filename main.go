@@ -2,9 +2,14 @@ package main
 
 import (
 	"fmt"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+	
 	fmt.Println("Starting Sims 4 Mod Manager...")
 	app := setupApp()
 	app.Run()
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ScriptFinding is one capability a script mod has no business having. These
+// are heuristics, plenty of them have innocent uses, but they're what the
+// known Sims 4 malware used.
+type ScriptFinding struct {
+	File       string `json:"file"`
+	Capability string `json:"capability"`
+	Detail     string `json:"detail"`
+}
+
+var dangerousModules = map[string]string{
+	"subprocess": "runs other programs",
+	"socket":     "opens network connections",
+	"ctypes":     "calls native code",
+	"urllib":     "talks to the internet",
+	"urllib2":    "talks to the internet",
+	"http":       "talks to the internet",
+	"requests":   "talks to the internet",
+	"ftplib":     "talks to the internet",
+	"smtplib":    "sends email",
+	"winreg":     "edits the Windows registry",
+	"_winreg":    "edits the Windows registry",
+	"_winapi":    "calls native code",
+}
+
+var dangerousCalls = map[string]string{
+	"popen":     "runs other programs",
+	"startfile": "runs other programs",
+	"spawnv":    "runs other programs",
+	"spawnl":    "runs other programs",
+	"execv":     "runs other programs",
+	"system":    "runs other programs",
+	// remove and unlink are list and Path methods too, only whole trees count
+	"rmtree": "deletes files",
+}
+
+// places a mod has no reason to write to, lowercase
+var outsidePaths = []string{
+	"appdata",
+	"programdata",
+	"system32",
+	"start menu",
+	`\startup`,
+	"/startup",
+	"userprofile",
+	"/etc/",
+	".bashrc",
+	".config/autostart",
+}
+
+var (
+	importPattern     = regexp.MustCompile(`(?m)^\s*(?:from\s+([\w.]+)\s+import|import\s+([\w., ]+))`)
+	identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+func moduleRoot(name string) string {
+	return strings.SplitN(strings.TrimSpace(name), ".", 2)[0]
+}
+
+// scanScriptContent looks at one .py or .pyc from inside an archive.
+func scanScriptContent(name string, data []byte) []ScriptFinding {
+	var modules, names []string
+	text := bytes.ToLower(data)
+	var unreadable error
+
+	if filepath.Ext(name) == ".py" {
+		for _, match := range importPattern.FindAllStringSubmatch(string(data), -1) {
+			if match[1] != "" {
+				modules = append(modules, moduleRoot(match[1]))
+			}
+			for _, module := range strings.Split(match[2], ",") {
+				// "import foo as bar"
+				if fields := strings.Fields(module); len(fields) > 0 {
+					modules = append(modules, moduleRoot(fields[0]))
+				}
+			}
+		}
+		names = identifierPattern.FindAllString(string(data), -1)
+	} else {
+		contents, err := readPyc(data)
+		switch {
+		case err == errPycVersion:
+			// the game won't load bytecode for another Python, it can't run
+			return nil
+		case err != nil:
+			unreadable = err
+		}
+		for _, module := range contents.imports {
+			modules = append(modules, moduleRoot(module))
+		}
+		names = contents.names
+
+		// __import__("socket") doesn't show up as an import, the module is
+		// just a string
+		for _, n := range names {
+			if n != "__import__" && n != "import_module" {
+				continue
+			}
+			for _, s := range contents.strings {
+				if _, ok := dangerousModules[moduleRoot(s)]; ok {
+					modules = append(modules, moduleRoot(s))
+				}
+			}
+			break
+		}
+		// paths only count from the constants, co_filename is wherever the
+		// author happened to build it
+		text = bytes.ToLower([]byte(strings.Join(contents.strings, "\x00")))
+	}
+
+	seen := make(map[string]bool)
+	var findings []ScriptFinding
+	add := func(capability, detail string) {
+		if seen[capability+detail] {
+			return
+		}
+		seen[capability+detail] = true
+		findings = append(findings, ScriptFinding{File: name, Capability: capability, Detail: detail})
+	}
+
+	for _, module := range modules {
+		if capability, ok := dangerousModules[module]; ok {
+			add(capability, "imports "+module)
+		}
+	}
+
+	if unreadable != nil {
+		// the game could still run what we couldn't read
+		add("couldn't be checked", "the bytecode couldn't be read: "+unreadable.Error())
+	}
+
+	hasExec, hasDecode := false, false
+	for _, n := range names {
+		lower := strings.ToLower(n)
+		if capability, ok := dangerousCalls[lower]; ok {
+			add(capability, "calls "+n)
+		}
+		switch lower {
+		case "exec", "eval":
+			hasExec = true
+		case "b64decode", "decompress", "fromhex", "a85decode":
+			hasDecode = true
+		}
+	}
+	if hasExec && hasDecode {
+		add("runs hidden code", "decodes data and executes it")
+	}
+
+	for _, path := range outsidePaths {
+		if bytes.Contains(text, []byte(path)) {
+			add("touches files outside the game folders", "mentions "+path)
+		}
+	}
+
+	return findings
+}
+
+// ScanScriptForMalware checks every Python file inside a .ts4script. Anything
+// that isn't a script archive has nothing to scan and comes back clean.
+func ScanScriptForMalware(path string) ([]ScriptFinding, error) {
+	if modExtension(path) != ".ts4script" {
+		return nil, nil
+	}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var findings []ScriptFinding
+	for _, file := range reader.File {
+		ext := filepath.Ext(file.Name)
+		if ext != ".py" && ext != ".pyc" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return findings, err
+		}
+		// scripts are small, anything huge is suspicious by itself but we don't
+		// want to read gigabytes to find out
+		data, err := io.ReadAll(io.LimitReader(rc, 16<<20))
+		rc.Close()
+		if err != nil {
+			return findings, fmt.Errorf("%s: %w", file.Name, err)
+		}
+
+		findings = append(findings, scanScriptContent(file.Name, data)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].File < findings[j].File })
+	return findings, nil
+}
+
+func formatFindings(findings []ScriptFinding) string {
+	var b strings.Builder
+	for _, finding := range findings {
+		fmt.Fprintf(&b, "%s: %s (%s)\n", finding.File, finding.Capability, finding.Detail)
+	}
+	return b.String()
+}
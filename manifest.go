@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// manifest.json remembers which files the manager installed, and for
+// CurseForge downloads what they are, so later features can tell them from CC
+// dropped into the folder by hand.
+const manifestFile = "manifest.json"
+
+type ManifestEntry struct {
+	Path      string    `json:"path"`
+	ModID     int       `json:"mod_id"`
+	ModName   string    `json:"mod_name"`
+	FileID    int       `json:"file_id"`
+	FileName  string    `json:"file_name"`
+	Installed time.Time `json:"installed"`
+	// translation packages point at the mod they translate, they're disabled,
+	// removed and carried over to updates together with it
+	Parent string `json:"parent,omitempty"`
+}
+
+var manifestMu sync.Mutex
+
+func loadManifest() ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return entries, err
+	}
+
+	err = json.Unmarshal(data, &entries)
+	return entries, err
+}
+
+func saveManifest(entries []ManifestEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(manifestFile, data, 0644)
+}
+
+// recordInstall adds or replaces the entry for a path.
+func recordInstall(entry ManifestEntry) error {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	entries, err := loadManifest()
+	if err != nil {
+		return err
+	}
+
+	entry.Path = filepath.Clean(entry.Path)
+	entry.Installed = time.Now()
+
+	// an update of a mod under a new file name takes the translations along,
+	// once the old file is gone. Another file of the same project installed
+	// next to it isn't an update, the translations stay with theirs.
+	if entry.ModID != 0 {
+		for _, old := range entries {
+			if old.ModID != entry.ModID || old.Path == entry.Path || modFileInstalled(old.Path) {
+				continue
+			}
+			for i := range entries {
+				if entries[i].Parent == old.Path {
+					entries[i].Parent = entry.Path
+				}
+			}
+		}
+	}
+
+	for i := range entries {
+		if entries[i].Path == entry.Path {
+			if entry.Parent == "" {
+				entry.Parent = entries[i].Parent
+			}
+			entries[i] = entry
+			return saveManifest(entries)
+		}
+	}
+	return saveManifest(append(entries, entry))
+}
+
+// modFileInstalled says whether a mod file is still there, enabled or not.
+func modFileInstalled(path string) bool {
+	for _, file := range []string{path, path + disabledSuffix} {
+		if _, err := os.Stat(file); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// forgetInstall drops the entry for a path, the file stays where it is.
+func forgetInstall(path string) error {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	entries, err := loadManifest()
+	if err != nil {
+		return err
+	}
+
+	path = filepath.Clean(path)
+	kept := entries[:0]
+	for _, entry := range entries {
+		if entry.Path != path {
+			kept = append(kept, entry)
+		}
+	}
+	return saveManifest(kept)
+}
+
+// TranslationsByParent maps each mod to the translation files installed for
+// it, enabled or not.
+func TranslationsByParent() (map[string][]string, error) {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	entries, err := loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	byParent := make(map[string][]string)
+	for _, entry := range entries {
+		if entry.Parent == "" {
+			continue
+		}
+		for _, file := range []string{entry.Path, entry.Path + disabledSuffix} {
+			if _, err := os.Stat(file); err == nil {
+				byParent[entry.Parent] = append(byParent[entry.Parent], file)
+			}
+		}
+	}
+	return byParent, nil
+}
+
+// TranslationsOf returns the translation files installed for a mod. path may
+// be the disabled name of the mod.
+func TranslationsOf(path string) ([]string, error) {
+	byParent, err := TranslationsByParent()
+	if err != nil {
+		return nil, err
+	}
+	return byParent[filepath.Clean(strings.TrimSuffix(path, disabledSuffix))], nil
+}
+
+// ManifestByPath returns the entries whose files are still where they were
+// installed. Disabled files count as well.
+func ManifestByPath() (map[string]ManifestEntry, error) {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	entries, err := loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	byPath := make(map[string]ManifestEntry)
+	for _, entry := range entries {
+		for _, path := range []string{entry.Path, entry.Path + disabledSuffix} {
+			if _, err := os.Stat(path); err == nil {
+				byPath[path] = entry
+			}
+		}
+	}
+	return byPath, nil
+}
+
+// InstalledFile finds where the manager installed a CurseForge mod, newest
+// install first if there are several.
+func InstalledFile(modID int) (string, ManifestEntry, bool) {
+	byPath, err := ManifestByPath()
+	if err != nil {
+		return "", ManifestEntry{}, false
+	}
+
+	var best string
+	var bestEntry ManifestEntry
+	for path, entry := range byPath {
+		if entry.ModID != modID || entry.Parent != "" {
+			continue
+		}
+		if best == "" || entry.Installed.After(bestEntry.Installed) {
+			best, bestEntry = path, entry
+		}
+	}
+	return best, bestEntry, best != ""
+}
@@ -12,11 +12,42 @@ type ModInfo struct {
 	InstallDate time.Time `json:"install_date"`
 	FilePath    string    `json:"file_path"`
 	FileSize    int64     `json:"file_size"`
+	Disabled    bool      `json:"disabled"`
+	Category    string    `json:"category,omitempty"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Languages   []string  `json:"languages,omitempty"`
+	Inode       uint64    `json:"-"`
+	// the watcher saw it added or changed outside the manager
+	Untracked bool `json:"-"`
 }
 
 type AppSettings struct {
 	ModsDirectory string `json:"mods_directory"`
 	ApiKey        string `json:"api_key"`
+	LastGameVersion string `json:"last_game_version"`
+	GameDirectory string `json:"game_directory"`
+	OwnedPacks    []string `json:"owned_packs"`
+	Installs      []GameInstall `json:"installs"`
+	OptionsFlags  map[string]OptionsFlags `json:"options_flags"`
+	AutoCleanCache bool `json:"auto_clean_cache"`
+	LaunchCommand string `json:"launch_command"`
+	GameLanguage  string `json:"game_language"`
+	// the last filters used in the Browse tab
+	BrowseFilters BrowseFilters `json:"browse_filters"`
+}
+
+// OptionsFlags are the Options.ini flags we want for a Mods directory. There
+// are no profiles, so each install's Mods directory gets its own set.
+type OptionsFlags struct {
+	ModsEnabled       bool `json:"mods_enabled"`
+	ScriptModsEnabled bool `json:"script_mods_enabled"`
+}
+
+type GameInstall struct {
+	Name          string `json:"name"`
+	Source        string `json:"source"`
+	GameDirectory string `json:"game_directory"`
+	ModsDirectory string `json:"mods_directory"`
 }
 
 var DefaultModsPath = filepath.Join(os.Getenv("HOME"), ".steam", "steam", "steamapps", "compatdata", "1222670", "pfx", "drive_c", "users", "steamuser", "Documents", "Electronic Arts", "The Sims 4", "Mods")
@@ -24,6 +55,10 @@ var DefaultModsPath = filepath.Join(os.Getenv("HOME"), ".steam", "steam", "steam
 func LoadSettings() (AppSettings, error) {
 	settings := AppSettings{
 		ModsDirectory: DefaultModsPath,
+		GameDirectory: DefaultGamePath,
+		LaunchCommand: defaultLaunchCommand,
+		GameLanguage:  defaultGameLanguage,
+		BrowseFilters: defaultBrowseFilters(),
 	}
 	
 	env := loadEnvFile()
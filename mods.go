@@ -1,27 +1,62 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 )
 
+const allCategories = "All categories"
+
+// the Mods tab filters, the per category totals above the list and the last
+// scan the filters are applied to
+var (
+	modsCategoryFilter = allCategories
+	modsSearchText     string
+	modsSummaryLabel   *widget.Label
+	installedMods      []ModInfo
+	installedInfos     map[string]PackageInfo
+	thumbnailsMu       sync.Mutex
+)
+
+// the running scan, only touched from the UI thread
+var (
+	modsScanCancel     context.CancelFunc
+	modsScanGeneration int
+	modsScanRow        *fyne.Container
+	modsScanLabel      *widget.Label
+	modsScanBar        *widget.ProgressBar
+)
+
+// picks up changes made to the Mods folder outside the app
+var modsWatcher *ModsWatcher
+
+const thumbnailSize = 64
+
 func setupModsTab() fyne.CanvasObject {
 	modsList := widget.NewList(
 		func() int { return 0 },
 		func() fyne.CanvasObject {
+			thumbnail := canvas.NewImageFromResource(theme.FileIcon())
+			thumbnail.FillMode = canvas.ImageFillContain
+			thumbnail.SetMinSize(fyne.NewSize(thumbnailSize, thumbnailSize))
 			return container.NewBorder(
-				nil, nil, nil, widget.NewButton("Remove", func() {}),
+				nil, nil, thumbnail,
+				container.NewHBox(widget.NewButton("Tuning", func() {}), widget.NewButton("Disable", func() {}), widget.NewButton("Remove", func() {})),
 				container.NewVBox(
 					widget.NewLabel("Mod Name"),
 					container.NewHBox(widget.NewIcon(theme.InfoIcon()), widget.NewLabel("Install Date")),
@@ -32,7 +67,38 @@ func setupModsTab() fyne.CanvasObject {
 		func(id widget.ListItemID, item fyne.CanvasObject) {},
 	)
 
+	modsSummaryLabel = widget.NewLabel("")
+	modsSummaryLabel.Wrapping = fyne.TextWrapWord
+	
+	categorySelect := widget.NewSelect(append([]string{allCategories}, categoryOrder...), func(category string) {
+		modsCategoryFilter = category
+		showInstalledMods(modsList)
+	})
+	categorySelect.Selected = modsCategoryFilter
+	
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search by file or in-game name")
+	searchEntry.OnChanged = func(text string) {
+		modsSearchText = text
+		showInstalledMods(modsList)
+	}
+	
+	modsScanLabel = widget.NewLabel("")
+	modsScanBar = widget.NewProgressBar()
+	cancelScanButton := widget.NewButton("Cancel", func() {
+		if modsScanCancel != nil {
+			modsScanCancel()
+		}
+	})
+	modsScanRow = container.NewBorder(nil, nil, modsScanLabel, cancelScanButton, modsScanBar)
+	modsScanRow.Hide()
+	
+	optionsRow, refreshOptions := setupOptionsRow()
+	resourceCfgRow, refreshResourceCfg := setupResourceCfgRow()
+	
 	refreshButton := widget.NewButton("Refresh Mods", func() {
+		refreshOptions()
+		refreshResourceCfg()
 		refreshModsList(modsList)
 	})
 	
@@ -40,25 +106,406 @@ func setupModsTab() fyne.CanvasObject {
 		showModBrowser(modsList)
 	})
 	
+	patchDayButton := widget.NewButton("Patch Day", func() {
+		showPatchDay(modsList)
+	})
+	
+	duplicatesButton := widget.NewButton("Duplicates", func() {
+		showDuplicates(modsList)
+	})
+	
+	translationsButton := widget.NewButton("Translations", func() {
+		showTranslations(modsList)
+	})
+	
+	undoButton := widget.NewButton("Undo", func() {
+		undoLastOperation(modsList)
+	})
+	
+	playButton := widget.NewButton("Play", func() {
+		playGame()
+	})
+	
+	go checkForPatch(modsList)
+	
+	if settings, err := LoadSettings(); err == nil {
+		watchModsFolder(settings.ModsDirectory, modsList)
+	}
+	
+	gameLabel := widget.NewLabel("")
+	gameLabel.Wrapping = fyne.TextWrapWord
+	showGameState := func(running bool) {
+		if !running {
+			gameLabel.Hide()
+			return
+		}
+		text := "The game is running. Changes to the Mods folder are queued until it exits"
+		if pending := pendingOpsCount(); pending > 0 {
+			text += fmt.Sprintf(" (%d queued)", pending)
+		}
+		gameLabel.SetText(text + ".")
+		gameLabel.Show()
+	}
+	showGameState(IsGameRunning())
+	
+	onGameStateChange(func(running bool) {
+		fyne.Do(func() {
+			showGameState(running)
+			if !running {
+				refreshOptions()
+				refreshModsList(modsList)
+			}
+		})
+	})
+	
 	return container.NewBorder(
-		widget.NewLabel("Installed Mods"),
-		container.NewHBox(refreshButton, installButton),
+		container.NewVBox(
+			container.NewBorder(nil, nil, widget.NewLabel("Installed Mods"), categorySelect),
+			searchEntry, modsSummaryLabel, modsScanRow, gameLabel, optionsRow, resourceCfgRow,
+		),
+		container.NewHBox(refreshButton, installButton, patchDayButton, duplicatesButton, translationsButton, undoButton, playButton),
 		nil, nil, container.NewVScroll(modsList),
 	)
 }
 
+func undoLastOperation(list *widget.List) {
+	window := fyne.CurrentApp().Driver().AllWindows()[0]
+	
+	operation, ok, err := LastOperation()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	if !ok {
+		dialog.ShowInformation("Undo", "Nothing to undo.", window)
+		return
+	}
+	
+	dialog.NewConfirm("Undo", "Undo \""+describeOperation(operation)+"\" from "+operation[0].Time.Format("2006-01-02 15:04")+"?", func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if _, err := UndoLastOperation(); err != nil {
+			dialog.ShowError(err, window)
+		}
+		refreshModsList(list)
+	}, window).Show()
+}
+
+// playGame runs the pre-launch checks and starts the game. Blockers stop the
+// launch, warnings can be skipped.
+func playGame() {
+	window := fyne.CurrentApp().Driver().AllWindows()[0]
+	
+	settings, err := LoadSettings()
+	if err != nil {
+		dialog.ShowError(err, window)
+		return
+	}
+	
+	progress := dialog.NewProgressInfinite("Play", "Running pre-launch checks...", window)
+	progress.Show()
+	
+	go func() {
+		results, worst := RunLaunchChecks(settings)
+		fyne.Do(func() {
+			progress.Hide()
+			
+			launch := func() {
+				if err := LaunchGame(settings); err != nil {
+					dialog.ShowError(err, window)
+				}
+			}
+			if worst == SeverityOK {
+				launch()
+				return
+			}
+			
+			report := widget.NewLabel(formatCheckResults(results))
+			report.Wrapping = fyne.TextWrapWord
+			scroll := container.NewVScroll(report)
+			scroll.SetMinSize(fyne.NewSize(600, 300))
+			
+			if worst == SeverityBlocker {
+				dialog.NewCustom("Can't Launch", "Close", scroll, window).Show()
+				return
+			}
+			dialog.NewCustomConfirm("Launch Warnings", "Launch Anyway", "Cancel", scroll, func(confirmed bool) {
+				if confirmed {
+					launch()
+				}
+			}, window).Show()
+		})
+	}()
+}
+
+// setupResourceCfgRow warns in the Mods tab when Resource.cfg is broken or
+// packages are too deep for it. The returned func rechecks.
+func setupResourceCfgRow() (fyne.CanvasObject, func()) {
+	warningLabel := widget.NewLabel("")
+	warningLabel.Wrapping = fyne.TextWrapWord
+
+	var refresh func()
+
+	regenerateButton := widget.NewButton("Regenerate Resource.cfg", func() {
+		settings, err := LoadSettings()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		dialog.NewConfirm(
+			"Regenerate Resource.cfg",
+			"Replace Resource.cfg with the game's default? The old one is kept as Resource.cfg.bak.",
+			func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				if err := WriteDefaultResourceCfg(settings.ModsDirectory); err != nil {
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+					return
+				}
+				refresh()
+			},
+			fyne.CurrentApp().Driver().AllWindows()[0],
+		).Show()
+	})
+
+	row := container.NewBorder(nil, nil, nil, regenerateButton, warningLabel)
+	row.Hide()
+
+	// walking the Mods tree for unreachable packages takes a while on a big
+	// folder, so it runs in the background and only the newest refresh shows
+	generation := 0
+	show := func(gen int, text string) {
+		fyne.Do(func() {
+			if gen != generation {
+				return
+			}
+			if text == "" {
+				row.Hide()
+				return
+			}
+			warningLabel.SetText(text)
+			row.Show()
+		})
+	}
+
+	refresh = func() {
+		generation++
+		gen := generation
+		go func() {
+			settings, err := LoadSettings()
+			if err != nil {
+				return
+			}
+
+			cfg, err := ParseResourceCfg(settings.ModsDirectory)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					show(gen, "Can't read Resource.cfg: "+err.Error())
+				} else if dirExists(settings.ModsDirectory) {
+					show(gen, "Resource.cfg is missing, the game won't load packages in subfolders.")
+				}
+				return
+			}
+
+			warnings := cfg.Problems
+			unreachable, err := UnreachablePackages(settings.ModsDirectory, cfg)
+			if err == nil && len(unreachable) > 0 {
+				warnings = append(warnings, fmt.Sprintf("%d packages are deeper than Resource.cfg allows (%d folders) and won't load, e.g. %s",
+					len(unreachable), cfg.MaxDepth(), filepath.Base(unreachable[0])))
+			}
+
+			if len(warnings) == 0 {
+				show(gen, "")
+				return
+			}
+			show(gen, "Resource.cfg: "+strings.Join(warnings, "; "))
+		}()
+	}
+
+	refresh()
+
+	return row, refresh
+}
+
+// refreshModsList scans in the background with the progress above the list.
+// A refresh while one is running replaces it.
 func refreshModsList(list *widget.List) {
 	settings, err := LoadSettings()
 	if err != nil {
 		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
 		return
 	}
+	
+	if modsScanCancel != nil {
+		modsScanCancel()
+	}
+	watchModsFolder(settings.ModsDirectory, list)
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	modsScanCancel = cancel
+	modsScanGeneration++
+	generation := modsScanGeneration
+	
+	showScanProgress(ScanProgress{Stage: "Looking for mods"})
+	progress := func(p ScanProgress) {
+		fyne.Do(func() {
+			if generation == modsScanGeneration {
+				showScanProgress(p)
+			}
+		})
+	}
+	
+	go func() {
+		allMods, err := scanModsContext(ctx, settings.ModsDirectory, progress)
+		if err == nil {
+			// only new and changed files get read, everything else comes from the index
+			if _, indexErr := UpdateModIndexContext(ctx, settings.ModsDirectory, allMods, progress); indexErr != nil {
+				if ctx.Err() != nil {
+					err = indexErr
+				} else {
+					fmt.Printf("Failed to save the mod index: %v\n", indexErr)
+				}
+			}
+		}
+		
+		infos := make(map[string]PackageInfo)
+		if err == nil {
+			untracked, untrackedErr := UntrackedMods()
+			if untrackedErr != nil {
+				fmt.Printf("Failed to read untracked mods: %v\n", untrackedErr)
+			}
+			for i := range allMods {
+				allMods[i].Untracked = untracked[allMods[i].FilePath]
+				info := modPackageInfo(allMods[i])
+				infos[allMods[i].FilePath] = info
+				allMods[i].Category = info.Category
+				allMods[i].DisplayName = info.DisplayName
+				allMods[i].Languages = info.Languages
+			}
+		}
+		
+		fyne.Do(func() {
+			if generation != modsScanGeneration {
+				return
+			}
+			cancel()
+			modsScanCancel = nil
+			hideScanProgress()
+			
+			if errors.Is(err, context.Canceled) {
+				if modsSummaryLabel != nil {
+					modsSummaryLabel.SetText("Scan cancelled, this is the last complete scan.")
+				}
+				return
+			}
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			
+			installedMods, installedInfos = allMods, infos
+			showInstalledMods(list)
+			
+			// extracting is slow the first time, rows pick the thumbnails up when it's done
+			go func() {
+				if !thumbnailsMu.TryLock() {
+					return
+				}
+				defer thumbnailsMu.Unlock()
+				if cacheThumbnails(allMods) {
+					fyne.Do(list.Refresh)
+				}
+			}()
+		})
+	}()
+}
 
-	mods, err := scanMods(settings.ModsDirectory)
+// watchModsFolder (re)starts the watcher when the Mods folder isn't watched
+// yet or was switched in the settings. Changes rescan the folder, the index
+// only rereads what changed.
+func watchModsFolder(modsDir string, list *widget.List) {
+	if modsWatcher != nil && modsWatcher.modsDir == modsDir {
+		return
+	}
+	if modsWatcher != nil {
+		modsWatcher.Stop()
+		modsWatcher = nil
+	}
+	
+	watcher, err := StartModsWatcher(modsDir, func(paths []string) {
+		fmt.Printf("Mods folder changed (%d paths), rescanning\n", len(paths))
+		recordOutsideChanges(paths)
+		fyne.Do(func() {
+			refreshModsList(list)
+		})
+	})
 	if err != nil {
-		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		fmt.Printf("Failed to watch the Mods folder: %v\n", err)
 		return
 	}
+	modsWatcher = watcher
+}
+
+func showScanProgress(p ScanProgress) {
+	if modsScanRow == nil {
+		return
+	}
+	if p.Total > 0 {
+		modsScanLabel.SetText(fmt.Sprintf("%s: %d of %d", p.Stage, p.Done, p.Total))
+		modsScanBar.SetValue(float64(p.Done) / float64(p.Total))
+	} else {
+		modsScanLabel.SetText(fmt.Sprintf("%s: %d found", p.Stage, p.Done))
+		modsScanBar.SetValue(0)
+	}
+	modsScanRow.Show()
+}
+
+func hideScanProgress() {
+	if modsScanRow != nil {
+		modsScanRow.Hide()
+	}
+}
+
+// showInstalledMods applies the category and search filters to the last scan.
+func showInstalledMods(list *widget.List) {
+	allMods := installedMods
+	query := strings.ToLower(strings.TrimSpace(modsSearchText))
+	
+	if modsSummaryLabel != nil {
+		var parts []string
+		for _, total := range CategoryTotals(allMods) {
+			parts = append(parts, fmt.Sprintf("%s of %s (%d)", formatFileSize(total.Size), strings.ToLower(total.Category), total.Files))
+		}
+		untracked := 0
+		for _, mod := range allMods {
+			if mod.Untracked {
+				untracked++
+			}
+		}
+		if untracked > 0 {
+			parts = append(parts, fmt.Sprintf("%d untracked (added or changed outside the manager)", untracked))
+		}
+		modsSummaryLabel.SetText(strings.Join(parts, ", "))
+	}
+	
+	// grouped by category, newest first within each
+	rank := make(map[string]int)
+	for i, category := range categoryOrder {
+		rank[category] = i
+	}
+	var mods []ModInfo
+	for _, mod := range allMods {
+		if modsCategoryFilter != allCategories && mod.Category != modsCategoryFilter {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(mod.Name), query) && !installedInfos[mod.FilePath].Matches(query) {
+			continue
+		}
+		mods = append(mods, mod)
+	}
+	sort.SliceStable(mods, func(i, j int) bool { return rank[mods[i].Category] < rank[mods[j].Category] })
 
 	list.Length = func() int { return len(mods) }
 	list.UpdateItem = func(id widget.ListItemID, item fyne.CanvasObject) {
@@ -68,16 +515,86 @@ func refreshModsList(list *widget.List) {
 		innerContainer := container.Objects[0].(*fyne.Container)
 		
 		nameLabel := innerContainer.Objects[0].(*widget.Label)
-		nameLabel.SetText(mod.Name)
+		name := mod.Name
+		if mod.DisplayName != "" {
+			name = mod.DisplayName + "  (" + mod.Name + ")"
+		}
+		if mod.Disabled {
+			name += " (disabled)"
+		}
+		if mod.Untracked {
+			name += " (untracked)"
+		}
+		nameLabel.SetText(name)
 		
 		dateContainer := innerContainer.Objects[1].(*fyne.Container)
 		dateLabel := dateContainer.Objects[1].(*widget.Label)
 		dateLabel.SetText(mod.InstallDate.Format("2006-01-02 15:04:05"))
 		
 		sizeLabel := innerContainer.Objects[2].(*widget.Label)
-		sizeLabel.SetText(formatFileSize(mod.FileSize))
+		sizeLabel.SetText(formatFileSize(mod.FileSize) + "  " + mod.Category + formatLanguages(mod.Languages))
+		
+		thumbnail := container.Objects[1].(*canvas.Image)
+		if path, _ := CachedThumbnail(mod); path != "" {
+			thumbnail.Resource = nil
+			thumbnail.File = path
+		} else {
+			thumbnail.File = ""
+			thumbnail.Resource = theme.FileIcon()
+		}
+		thumbnail.Refresh()
+		
+		buttons := container.Objects[2].(*fyne.Container)
 		
-		removeButton := container.Objects[1].(*widget.Button)
+		tuningButton := buttons.Objects[0].(*widget.Button)
+		if modExtension(mod.FilePath) == ".package" {
+			tuningButton.OnTapped = func() {
+				showTuningViewer(mod.FilePath)
+			}
+			tuningButton.Show()
+		} else {
+			tuningButton.Hide()
+		}
+		
+		toggleButton := buttons.Objects[1].(*widget.Button)
+		if mod.Disabled {
+			toggleButton.SetText("Enable")
+		} else {
+			toggleButton.SetText("Disable")
+		}
+		toggleButton.OnTapped = func() {
+			if IsGameRunning() {
+				translations, _ := TranslationsOf(mod.FilePath)
+				group := newJournalGroup()
+				for _, file := range append([]string{mod.FilePath}, translations...) {
+					// a translation only needs renaming if it isn't already where the mod is going
+					if file != mod.FilePath && strings.HasSuffix(file, disabledSuffix) != mod.Disabled {
+						continue
+					}
+					err := queuePendingOp(PendingOp{
+						Kind:        PendingRename,
+						Source:      file,
+						Target:      toggledModPath(file),
+						Description: toggleButton.Text + " " + filepath.Base(file),
+						Group:       group,
+					})
+					if err != nil {
+						dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+						return
+					}
+				}
+				dialog.ShowInformation("Game Running", mod.Name+" will be changed after the game exits.", fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			if _, err := setModEnabled(mod.FilePath, mod.Disabled); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
+			onModsChanged()
+			refreshModsList(list)
+		}
+		
+		removeButton := buttons.Objects[2].(*widget.Button)
 		removeButton.OnTapped = func() {
 			removeMod(mod, list)
 		}
@@ -86,27 +603,23 @@ func refreshModsList(list *widget.List) {
 	list.Refresh()
 }
 
+func formatLanguages(languages []string) string {
+	switch {
+	case len(languages) == 0:
+		return ""
+	case len(languages) <= 3:
+		return "  " + strings.Join(languages, ", ")
+	}
+	return fmt.Sprintf("  %s +%d languages", languages[0], len(languages)-1)
+}
+
 func scanMods(directory string) ([]ModInfo, error) {
-	var mods []ModInfo
-	
-	err := filepath.Walk(directory, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		
-		if !info.IsDir() && (filepath.Ext(path) == ".package" || filepath.Ext(path) == ".ts4script") {
-			mod := ModInfo{
-				Name:        filepath.Base(path),
-				InstallDate: info.ModTime(),
-				FilePath:    path,
-				FileSize:    info.Size(),
-			}
-			mods = append(mods, mod)
-		}
-		
-		return nil
-	})
-	
+	return scanModsContext(context.Background(), directory, nil)
+}
+
+// scanModsContext walks the Mods folder on the worker pool, newest first.
+func scanModsContext(ctx context.Context, directory string, progress ProgressFunc) ([]ModInfo, error) {
+	mods, err := walkModFiles(ctx, directory, progress)
 	if err != nil {
 		return nil, err
 	}
@@ -120,6 +633,67 @@ func scanMods(directory string) ([]ModInfo, error) {
 	return mods, nil
 }
 
+// disabled mods keep their folder and just get a suffix the game won't load
+const disabledSuffix = ".disabled"
+
+func modExtension(path string) string {
+	return filepath.Ext(strings.TrimSuffix(path, disabledSuffix))
+}
+
+func isModFile(path string) bool {
+	ext := modExtension(path)
+	return ext == ".package" || ext == ".ts4script"
+}
+
+func toggledModPath(path string) string {
+	if strings.HasSuffix(path, disabledSuffix) {
+		return strings.TrimSuffix(path, disabledSuffix)
+	}
+	return path + disabledSuffix
+}
+
+// setModEnabled renames a mod file in or out of its disabled state and returns
+// the new path.
+func setModEnabled(path string, enabled bool) (string, error) {
+	return setModEnabledIn(newJournalGroup(), path, enabled)
+}
+
+// setModEnabledIn is setModEnabled as part of a journal group, the mod and its
+// translations are undone as one.
+func setModEnabledIn(group int64, path string, enabled bool) (string, error) {
+	disabled := strings.HasSuffix(path, disabledSuffix)
+	if enabled == !disabled {
+		return path, nil
+	}
+	
+	if IsGameRunning() {
+		return path, errGameRunning
+	}
+	
+	newPath := toggledModPath(path)
+	
+	if _, err := os.Stat(newPath); err == nil {
+		return path, fmt.Errorf("can't rename %s, %s already exists", filepath.Base(path), filepath.Base(newPath))
+	}
+	
+	if err := checkCaseCollision(filepath.Dir(newPath), filepath.Base(newPath)); err != nil {
+		return path, err
+	}
+	
+	if err := journaledMove(group, path, newPath); err != nil {
+		return path, err
+	}
+	
+	// translations follow the mod they translate
+	translations, _ := TranslationsOf(path)
+	for _, translation := range translations {
+		if _, err := setModEnabledIn(group, translation, enabled); err != nil {
+			fmt.Printf("Failed to toggle translation %s: %v\n", translation, err)
+		}
+	}
+	return newPath, nil
+}
+
 func saveRecentMods(mods []ModInfo) error {
 	data, err := json.Marshal(mods)
 	if err != nil {
@@ -130,16 +704,39 @@ func saveRecentMods(mods []ModInfo) error {
 }
 
 func removeMod(mod ModInfo, list *widget.List) {
+	translations, _ := TranslationsOf(mod.FilePath)
+	
+	message := "Are you sure you want to remove " + mod.Name + "? It goes to the trash and can be undone."
+	if len(translations) > 0 {
+		message = fmt.Sprintf("Are you sure you want to remove %s and its %d translations? They go to the trash and can be undone.", mod.Name, len(translations))
+	}
+	
 	confirmDialog := dialog.NewConfirm(
 		"Confirm Removal",
-		"Are you sure you want to remove " + mod.Name + "?",
+		message,
 		func(confirmed bool) {
 			if confirmed {
-				err := os.Remove(mod.FilePath)
-				if err != nil {
-					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				files := append([]string{mod.FilePath}, translations...)
+				group := newJournalGroup()
+				if IsGameRunning() {
+					for _, file := range files {
+						err := queuePendingOp(PendingOp{Kind: PendingDelete, Target: file, Description: "remove " + filepath.Base(file), Group: group})
+						if err != nil {
+							dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+							return
+						}
+					}
+					dialog.ShowInformation("Game Running", mod.Name+" will be removed after the game exits.", fyne.CurrentApp().Driver().AllWindows()[0])
 					return
 				}
+				for _, file := range files {
+					err := journaledDelete(group, file)
+					if err != nil {
+						dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+						break
+					}
+				}
+				onModsChanged()
 				refreshModsList(list)
 			}
 		},
@@ -168,7 +765,12 @@ func installMod(reader fyne.URIReadCloser, list *widget.List) {
 	}
 	
 	filename := filepath.Base(reader.URI().Path())
-	targetPath := filepath.Join(settings.ModsDirectory, filename)
+	
+	targetPath, err := modInstallPath(settings.ModsDirectory, filename)
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
 	
 	if _, err := os.Stat(targetPath); err == nil {
 		confirmDialog := dialog.NewConfirm(
@@ -176,19 +778,25 @@ func installMod(reader fyne.URIReadCloser, list *widget.List) {
 			"A mod with the name " + filename + " already exists. Do you want to overwrite it?",
 			func(confirmed bool) {
 				if confirmed {
-					copyModFile(reader, targetPath, list)
+					copyModFile(reader, settings.ModsDirectory, targetPath, list)
 				}
 			},
 			fyne.CurrentApp().Driver().AllWindows()[0],
 		)
 		confirmDialog.Show()
 	} else {
-		copyModFile(reader, targetPath, list)
+		copyModFile(reader, settings.ModsDirectory, targetPath, list)
 	}
 }
 
-func copyModFile(reader fyne.URIReadCloser, targetPath string, list *widget.List) {
-	targetFile, err := os.Create(targetPath)
+func copyModFile(reader fyne.URIReadCloser, modsDir, targetPath string, list *widget.List) {
+	writePath, err := stagingPath(targetPath)
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+	
+	targetFile, err := os.Create(writePath)
 	if err != nil {
 		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
 		return
@@ -201,20 +809,25 @@ func copyModFile(reader fyne.URIReadCloser, targetPath string, list *widget.List
 		return
 	}
 	
-	dialog.ShowInformation("Mod Installed", "The mod has been successfully installed.", fyne.CurrentApp().Driver().AllWindows()[0])
+	targetFile.Close()
 	
-	refreshModsList(list)
-}
-
-func formatFileSize(size int64) string {
-	const unit = 1024
-	if size < unit {
-		return fmt.Sprintf("%d B", size)
+	outcome, entry, err := admitModFile(writePath, modsDir, targetPath, 0)
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
 	}
-	div, exp := int64(unit), 0
-	for n := size / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	switch outcome {
+	case AdmitQuarantined:
+		showQuarantinedNotice(entry)
+		return
+	case AdmitQueued:
+		dialog.ShowInformation("Game Running", "The mod is downloaded and will be installed after the game exits.", fyne.CurrentApp().Driver().AllWindows()[0])
+		return
 	}
-	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+	
+	onModsChanged()
+	
+	dialog.ShowInformation("Mod Installed", "The mod has been successfully installed.", fyne.CurrentApp().Driver().AllWindows()[0])
+	
+	refreshModsList(list)
 }
\ No newline at end of file
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// unzipping a mod pack or copying a folder of CC fires hundreds of events,
+// wait until the folder has been quiet this long before rescanning
+const modsSettleDelay = time.Second
+
+// ModsWatcher watches the Mods folder and everything under it and calls
+// onChange with the changed paths once things settle.
+type ModsWatcher struct {
+	modsDir  string
+	watcher  *fsnotify.Watcher
+	onChange func([]string)
+
+	mu      sync.Mutex
+	changed map[string]bool
+	timer   *time.Timer
+	done    chan struct{}
+}
+
+// StartModsWatcher starts watching modsDir. fsnotify doesn't watch
+// recursively, so every folder gets its own watch, new folders included.
+func StartModsWatcher(modsDir string, onChange func([]string)) (*ModsWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &ModsWatcher{
+		modsDir:  modsDir,
+		watcher:  watcher,
+		onChange: onChange,
+		changed:  make(map[string]bool),
+		done:     make(chan struct{}),
+	}
+	if err := w.addTree(modsDir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// addTree watches dir and the folders under it. Only failing on dir itself
+// is an error, a subfolder can be gone again by the time it's added.
+func (w *ModsWatcher) addTree(dir string) error {
+	if err := w.watcher.Add(dir); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := w.addTree(filepath.Join(dir, entry.Name())); err != nil {
+				fmt.Printf("Failed to watch %s: %v\n", entry.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *ModsWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// files moved in with the folder don't get events of their own
+					w.addTree(event.Name)
+					w.schedule(event.Name)
+					continue
+				}
+			}
+			// removed folders drop their watch by themselves, and their files
+			// are gone from the next scan, so only mod files matter here
+			if isModFile(event.Name) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				w.schedule(event.Name)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("Mods watcher error: %v\n", err)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *ModsWatcher) schedule(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.changed[path] = true
+	if w.timer != nil {
+		w.timer.Reset(modsSettleDelay)
+		return
+	}
+	w.timer = time.AfterFunc(modsSettleDelay, w.flush)
+}
+
+func (w *ModsWatcher) flush() {
+	w.mu.Lock()
+	paths := make([]string, 0, len(w.changed))
+	for path := range w.changed {
+		paths = append(paths, path)
+	}
+	w.changed = make(map[string]bool)
+	w.timer = nil
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	sort.Strings(paths)
+	if w.onChange != nil {
+		w.onChange(paths)
+	}
+}
+
+func (w *ModsWatcher) Stop() {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+
+	close(w.done)
+	w.watcher.Close()
+}
+
+// the manager's own changes to the Mods folder, so the watcher can tell them
+// from changes made outside it. Copies across filesystems take a while, the
+// window is generous.
+const ownChangeWindow = 30 * time.Second
+
+var (
+	ownChangesMu sync.Mutex
+	ownChanges   = make(map[string]time.Time)
+)
+
+// noteOwnChange marks paths the manager is about to change or just changed.
+func noteOwnChange(paths ...string) {
+	ownChangesMu.Lock()
+	defer ownChangesMu.Unlock()
+
+	now := time.Now()
+	for path, at := range ownChanges {
+		if now.Sub(at) > ownChangeWindow {
+			delete(ownChanges, path)
+		}
+	}
+	for _, path := range paths {
+		ownChanges[filepath.Clean(path)] = now
+	}
+}
+
+func isOwnChange(path string) bool {
+	ownChangesMu.Lock()
+	defer ownChangesMu.Unlock()
+	at, ok := ownChanges[filepath.Clean(path)]
+	return ok && time.Since(at) <= ownChangeWindow
+}
+
+// untracked_mods.json lists the mod files the watcher saw being added or
+// changed outside the manager, with when it noticed.
+const untrackedModsFile = "untracked_mods.json"
+
+var untrackedMu sync.Mutex
+
+func loadUntrackedLocked() (map[string]time.Time, error) {
+	untracked := make(map[string]time.Time)
+	data, err := os.ReadFile(untrackedModsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return untracked, nil
+		}
+		return untracked, err
+	}
+	err = json.Unmarshal(data, &untracked)
+	return untracked, err
+}
+
+func saveUntrackedLocked(untracked map[string]time.Time) error {
+	data, err := json.MarshalIndent(untracked, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(untrackedModsFile, data, 0644)
+}
+
+// UntrackedMods returns the files changed outside the manager that are still
+// there.
+func UntrackedMods() (map[string]bool, error) {
+	untrackedMu.Lock()
+	defer untrackedMu.Unlock()
+
+	untracked, err := loadUntrackedLocked()
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool)
+	for path := range untracked {
+		if _, err := os.Stat(path); err == nil {
+			paths[path] = true
+		}
+	}
+	return paths, nil
+}
+
+// carryUntracked is for the manager moving a file: whatever lands at target
+// is untracked only if what was at source was, like a disabled hand made
+// change.
+func carryUntracked(source, target string) {
+	untrackedMu.Lock()
+	defer untrackedMu.Unlock()
+
+	untracked, err := loadUntrackedLocked()
+	if err != nil {
+		return
+	}
+	source, target = filepath.Clean(source), filepath.Clean(target)
+	at, was := untracked[source]
+	_, is := untracked[target]
+	if !was && !is {
+		return
+	}
+	delete(untracked, source)
+	delete(untracked, target)
+	if was {
+		untracked[target] = at
+	}
+	if err := saveUntrackedLocked(untracked); err != nil {
+		fmt.Printf("Failed to save untracked mods: %v\n", err)
+	}
+}
+
+// recordOutsideChanges looks at what the watcher saw and marks the mod files
+// the manager didn't touch itself as untracked. An installed file rewritten
+// that way loses its manifest entry, whatever is there now isn't what the
+// manager installed. Returns the paths that became untracked.
+func recordOutsideChanges(paths []string) []string {
+	untrackedMu.Lock()
+	defer untrackedMu.Unlock()
+
+	untracked, err := loadUntrackedLocked()
+	if err != nil {
+		fmt.Printf("Failed to read untracked mods: %v\n", err)
+		return nil
+	}
+	manifest, err := ManifestByPath()
+	if err != nil {
+		fmt.Printf("Failed to read manifest: %v\n", err)
+	}
+
+	// a folder moved in arrives as one event, its mod files are what changed
+	var expanded []string
+	for _, path := range paths {
+		if isOwnChange(path) {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() {
+					expanded = append(expanded, file)
+				}
+				return nil
+			})
+			continue
+		}
+		expanded = append(expanded, path)
+	}
+
+	var changed []string
+	dirty := false
+	for _, path := range expanded {
+		if _, err := os.Stat(path); err != nil {
+			if _, ok := untracked[path]; ok {
+				delete(untracked, path)
+				dirty = true
+			}
+			continue
+		}
+		if !isModFile(path) {
+			continue
+		}
+
+		if entry, ok := manifest[path]; ok {
+			if err := forgetInstall(entry.Path); err != nil {
+				fmt.Printf("Failed to update manifest: %v\n", err)
+			}
+		}
+		if _, ok := untracked[path]; !ok {
+			fmt.Printf("%s was changed outside the manager, it's untracked now\n", filepath.Base(path))
+			changed = append(changed, path)
+		}
+		untracked[path] = time.Now()
+		dirty = true
+	}
+
+	if dirty {
+		if err := saveUntrackedLocked(untracked); err != nil {
+			fmt.Printf("Failed to save untracked mods: %v\n", err)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	optionsSection       = "options"
+	optionModsDisabled   = "modsdisabled"
+	optionScriptsEnabled = "scriptmodsenabled"
+)
+
+// OptionsIni keeps the file as raw lines so saving only touches the values we
+// changed. The game is picky about this file and resets it if it can't read it.
+type OptionsIni struct {
+	path    string
+	lines   []string
+	newline string
+}
+
+func optionsIniPath(modsDir string) string {
+	return filepath.Join(documentsDir(modsDir), "Options.ini")
+}
+
+func LoadOptionsIni(modsDir string) (*OptionsIni, error) {
+	path := optionsIniPath(modsDir)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	text := string(data)
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), newline)
+	return &OptionsIni{path: path, lines: lines, newline: newline}, nil
+}
+
+func parseIniKey(line string) (string, string, bool) {
+	parts := strings.SplitN(line, "=", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1]), true
+}
+
+func iniSection(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+		return strings.ToLower(strings.Trim(trimmed, "[]")), true
+	}
+	return "", false
+}
+
+// find returns the line index of key in the options section, or -1 and the
+// index a new key should be inserted at.
+func (o *OptionsIni) find(key string) (int, int) {
+	section := ""
+	insertAt := -1
+
+	for i, line := range o.lines {
+		if name, ok := iniSection(line); ok {
+			section = name
+			if section == optionsSection {
+				insertAt = i + 1
+			}
+			continue
+		}
+		if section != optionsSection {
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			insertAt = i + 1
+		}
+		if k, _, ok := parseIniKey(line); ok && k == key {
+			return i, insertAt
+		}
+	}
+
+	return -1, insertAt
+}
+
+func (o *OptionsIni) Get(key string) (string, bool) {
+	i, _ := o.find(key)
+	if i < 0 {
+		return "", false
+	}
+	_, value, _ := parseIniKey(o.lines[i])
+	return value, true
+}
+
+func (o *OptionsIni) Set(key, value string) {
+	i, insertAt := o.find(key)
+	if i >= 0 {
+		o.lines[i] = key + " = " + value
+		return
+	}
+
+	if insertAt < 0 {
+		o.lines = append(o.lines, "["+optionsSection+"]")
+		insertAt = len(o.lines)
+	}
+
+	o.lines = append(o.lines[:insertAt], append([]string{key + " = " + value}, o.lines[insertAt:]...)...)
+}
+
+// ModsEnabled is the "Enable Custom Content and Mods" checkbox in game.
+func (o *OptionsIni) ModsEnabled() bool {
+	value, ok := o.Get(optionModsDisabled)
+	return ok && value == "0"
+}
+
+func (o *OptionsIni) ScriptModsEnabled() bool {
+	value, ok := o.Get(optionScriptsEnabled)
+	return ok && value == "1"
+}
+
+func (o *OptionsIni) SetModsEnabled(enabled bool) {
+	o.Set(optionModsDisabled, boolFlag(!enabled))
+}
+
+func (o *OptionsIni) SetScriptModsEnabled(enabled bool) {
+	o.Set(optionScriptsEnabled, boolFlag(enabled))
+}
+
+func boolFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// Save backs up the old file and swaps the new one in with a rename so the game
+// never sees a half written Options.ini.
+func (o *OptionsIni) Save() error {
+	data := []byte(strings.Join(o.lines, o.newline) + o.newline)
+
+	if old, err := os.ReadFile(o.path); err == nil {
+		if err := os.WriteFile(o.path+".bak", old, 0644); err != nil {
+			return fmt.Errorf("failed to back up Options.ini: %w", err)
+		}
+	}
+
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.path)
+}
+
+// applyOptionsFlags writes the flags we remember for this Mods directory back
+// into Options.ini, for when a patch switched them off again.
+func applyOptionsFlags(modsDir string, flags OptionsFlags) error {
+	ini, err := LoadOptionsIni(modsDir)
+	if err != nil {
+		return err
+	}
+	ini.SetModsEnabled(flags.ModsEnabled)
+	ini.SetScriptModsEnabled(flags.ScriptModsEnabled)
+	return ini.Save()
+}
+
+// setupOptionsRow shows the two Options.ini flags in the Mods tab. The returned
+// func reloads them from disk.
+func setupOptionsRow() (fyne.CanvasObject, func()) {
+	warningLabel := widget.NewLabel("")
+	warningLabel.Wrapping = fyne.TextWrapWord
+	warningLabel.Hide()
+
+	var modsCheck, scriptsCheck *widget.Check
+	loading := false
+
+	save := func() {
+		if loading {
+			return
+		}
+		settings, err := LoadSettings()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		// the game writes Options.ini back when it exits, so edits now would be lost
+		if IsGameRunning() {
+			dialog.ShowError(errGameRunning, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		flags := OptionsFlags{ModsEnabled: modsCheck.Checked, ScriptModsEnabled: scriptsCheck.Checked}
+		if err := applyOptionsFlags(settings.ModsDirectory, flags); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to update Options.ini: %w", err), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+
+		if settings.OptionsFlags == nil {
+			settings.OptionsFlags = make(map[string]OptionsFlags)
+		}
+		settings.OptionsFlags[settings.ModsDirectory] = flags
+		SaveSettings(settings)
+	}
+
+	modsCheck = widget.NewCheck("Custom content enabled", func(bool) { save() })
+	scriptsCheck = widget.NewCheck("Script mods allowed", func(bool) { save() })
+
+	refresh := func() {
+		settings, err := LoadSettings()
+		if err != nil {
+			return
+		}
+
+		ini, err := LoadOptionsIni(settings.ModsDirectory)
+		if err != nil {
+			modsCheck.Disable()
+			scriptsCheck.Disable()
+			warningLabel.SetText("Options.ini not found, start the game once so it creates one.")
+			warningLabel.Show()
+			return
+		}
+
+		loading = true
+		modsCheck.Enable()
+		scriptsCheck.Enable()
+		modsCheck.SetChecked(ini.ModsEnabled())
+		scriptsCheck.SetChecked(ini.ScriptModsEnabled())
+		loading = false
+
+		var warnings []string
+		if !ini.ModsEnabled() {
+			warnings = append(warnings, "custom content is switched off, the game won't load anything in Mods")
+		} else if !ini.ScriptModsEnabled() {
+			warnings = append(warnings, "script mods are switched off, .ts4script mods won't load")
+		}
+		if flags, ok := settings.OptionsFlags[settings.ModsDirectory]; ok {
+			if flags.ModsEnabled != ini.ModsEnabled() || flags.ScriptModsEnabled != ini.ScriptModsEnabled() {
+				warnings = append(warnings, "the game changed these since you last set them (a patch does that), tick them again to restore")
+			}
+		}
+
+		if len(warnings) == 0 {
+			warningLabel.Hide()
+			return
+		}
+		warningLabel.SetText("Warning: " + strings.Join(warnings, "; "))
+		warningLabel.Show()
+	}
+
+	refresh()
+
+	return container.NewVBox(container.NewHBox(modsCheck, scriptsCheck), warningLabel), refresh
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PackageInfo is what the Mods tab shows about a package beyond its file
+// name, all read from the resources inside.
+type PackageInfo struct {
+	Category string
+	// the in-game name of the first item, with a count when there are more
+	DisplayName  string
+	Names        []string
+	Descriptions []string
+	Languages    []string
+}
+
+// Matches is the Mods tab search, over the in-game names and descriptions.
+func (info PackageInfo) Matches(query string) bool {
+	for _, text := range append(info.Names, info.Descriptions...) {
+		if strings.Contains(strings.ToLower(text), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzePackage reads a package once for its category and strings. Names
+// come from the string table keys in CAS parts and catalog objects, looked up
+// in English when it's there and in whatever language there is otherwise.
+func AnalyzePackage(path string) (PackageInfo, error) {
+	pkg, err := ReadDBPF(path)
+	if err != nil {
+		return PackageInfo{Category: CategoryUnknown}, err
+	}
+	return analyzeDBPF(pkg), nil
+}
+
+func analyzeDBPF(pkg *DBPFPackage) PackageInfo {
+	info := PackageInfo{Category: CategoryUnknown}
+
+	casParts := make(map[ResourceKey]CASPart)
+	var nameKeys, descriptionKeys []uint32
+	tables := make(map[byte]map[uint32]string)
+
+	for _, entry := range pkg.Entries {
+		switch entry.Key.Type {
+		case resourceCASPart:
+			data, err := pkg.ReadResource(entry)
+			if err != nil {
+				continue
+			}
+			part, err := parseCASPart(data)
+			if err != nil {
+				continue
+			}
+			casParts[entry.Key] = part
+			nameKeys = append(nameKeys, part.TitleKey)
+			descriptionKeys = append(descriptionKeys, part.DescriptionKey)
+		case resourceCatalogObject:
+			// version, common block version, then the name and description keys
+			data, err := pkg.ReadResource(entry)
+			if err != nil || len(data) < 16 {
+				continue
+			}
+			nameKeys = append(nameKeys, binary.LittleEndian.Uint32(data[8:]))
+			descriptionKeys = append(descriptionKeys, binary.LittleEndian.Uint32(data[12:]))
+		case resourceStringTable:
+			data, err := pkg.ReadResource(entry)
+			if err != nil {
+				continue
+			}
+			strs, err := ParseSTBL(data)
+			if err != nil {
+				continue
+			}
+			locale := stblLocale(entry.Key)
+			if tables[locale] == nil {
+				tables[locale] = make(map[uint32]string)
+			}
+			for key, text := range strs {
+				tables[locale][key] = text
+			}
+		}
+	}
+
+	info.Category = classifyPackage(pkg, casParts)
+
+	var locales []int
+	for locale := range tables {
+		locales = append(locales, int(locale))
+	}
+	sort.Ints(locales)
+	for _, locale := range locales {
+		info.Languages = append(info.Languages, localeName(byte(locale)))
+	}
+	if len(locales) == 0 {
+		return info
+	}
+
+	table := tables[0x00]
+	if table == nil {
+		table = tables[byte(locales[0])]
+	}
+	info.Names = lookupStrings(table, nameKeys)
+	info.Descriptions = lookupStrings(table, descriptionKeys)
+
+	if len(info.Names) > 0 {
+		info.DisplayName = info.Names[0]
+		if len(info.Names) > 1 {
+			info.DisplayName += fmt.Sprintf(" (+%d more)", len(info.Names)-1)
+		}
+	}
+	return info
+}
+
+func lookupStrings(table map[uint32]string, keys []uint32) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, key := range keys {
+		text := strings.TrimSpace(table[key])
+		if key == 0 || text == "" || seen[text] {
+			continue
+		}
+		seen[text] = true
+		out = append(out, text)
+	}
+	return out
+}
+
+// modPackageInfo is what the index knows about a mod file, the file is only
+// read when the index doesn't have it yet.
+func modPackageInfo(mod ModInfo) PackageInfo {
+	if entry, ok := indexedMod(mod); ok && entry.Info != nil {
+		return *entry.Info
+	}
+	entry := indexModFile(mod)
+	return *entry.Info
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var DefaultGamePath = filepath.Join(os.Getenv("HOME"), ".steam", "steam", "steamapps", "common", "The Sims 4")
+
+// pack folders in the install dir are named after their codes (EP01, GP04, ...)
+var packCodePattern = regexp.MustCompile(`(?i)\b(EP|GP|SP|FP)(\d{2})\b`)
+
+var sentencePattern = regexp.MustCompile(`[.!?\n]+`)
+
+// words that make a sentence about packs, whole words so "package" and
+// "unpack" don't count
+var packCuePattern = regexp.MustCompile(`(?i)\b(?:requir\w*|need(?:s|ed)?|packs?)\b`)
+
+// not every kit is in here, anything missing just shows up by its code
+var packNames = map[string]string{
+	"EP01": "Get to Work",
+	"EP02": "Get Together",
+	"EP03": "City Living",
+	"EP04": "Cats & Dogs",
+	"EP05": "Seasons",
+	"EP06": "Get Famous",
+	"EP07": "Island Living",
+	"EP08": "Discover University",
+	"EP09": "Eco Lifestyle",
+	"EP10": "Snowy Escape",
+	"EP11": "Cottage Living",
+	"EP12": "High School Years",
+	"EP13": "Growing Together",
+	"EP14": "Horse Ranch",
+	"EP15": "For Rent",
+	"EP16": "Lovestruck",
+	"EP17": "Life & Death",
+	"EP18": "Businesses & Hobbies",
+	"GP01": "Outdoor Retreat",
+	"GP02": "Spa Day",
+	"GP03": "Dine Out",
+	"GP04": "Vampires",
+	"GP05": "Parenthood",
+	"GP06": "Jungle Adventure",
+	"GP07": "StrangerVille",
+	"GP08": "Realm of Magic",
+	"GP09": "Journey to Batuu",
+	"GP10": "Dream Home Decorator",
+	"GP11": "My Wedding Stories",
+	"GP12": "Werewolves",
+	"SP01": "Luxury Party Stuff",
+	"SP02": "Perfect Patio Stuff",
+	"SP03": "Cool Kitchen Stuff",
+	"SP04": "Spooky Stuff",
+	"SP05": "Movie Hangout Stuff",
+	"SP06": "Romantic Garden Stuff",
+	"SP07": "Kids Room Stuff",
+	"SP08": "Backyard Stuff",
+	"SP09": "Vintage Glamour Stuff",
+	"SP10": "Bowling Night Stuff",
+	"SP11": "Fitness Stuff",
+	"SP12": "Toddler Stuff",
+	"SP13": "Laundry Day Stuff",
+	"SP14": "My First Pet Stuff",
+	"SP15": "Moschino Stuff",
+	"SP16": "Tiny Living Stuff",
+	"SP17": "Nifty Knitting",
+	"SP18": "Paranormal Stuff",
+	"FP01": "Holiday Celebration",
+}
+
+func packLabel(code string) string {
+	if name, ok := packNames[code]; ok {
+		return code + " " + name
+	}
+	return code
+}
+
+// DetectInstalledPacks lists the pack folders in the game install directory.
+func DetectInstalledPacks(gameDir string) ([]string, error) {
+	entries, err := os.ReadDir(gameDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var packs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := strings.ToUpper(entry.Name())
+		if packCodePattern.FindString(name) == name {
+			packs = append(packs, name)
+		}
+	}
+
+	sort.Strings(packs)
+	return packs, nil
+}
+
+// RequiredPacks guesses which packs a mod needs. Pack codes count anywhere,
+// pack names only count in category names or in sentences that talk about
+// requirements, otherwise every mod mentioning "seasons" would need EP05.
+func RequiredPacks(mod Mod, description string) []string {
+	found := make(map[string]bool)
+
+	for _, cat := range mod.Categories {
+		for code, name := range packNames {
+			if strings.EqualFold(cat.Name, name) {
+				found[code] = true
+			}
+		}
+		for _, m := range packCodePattern.FindAllStringSubmatch(cat.Name, -1) {
+			found[strings.ToUpper(m[1]+m[2])] = true
+		}
+	}
+
+	text := mod.Summary + ". " + StripHTML(description)
+	for _, m := range packCodePattern.FindAllStringSubmatch(text, -1) {
+		found[strings.ToUpper(m[1]+m[2])] = true
+	}
+
+	for _, sentence := range sentencePattern.Split(text, -1) {
+		if !packCuePattern.MatchString(sentence) {
+			continue
+		}
+		lower := strings.ToLower(sentence)
+		for code, name := range packNames {
+			if strings.Contains(lower, strings.ToLower(name)) {
+				found[code] = true
+			}
+		}
+	}
+
+	var packs []string
+	for code := range found {
+		packs = append(packs, code)
+	}
+	sort.Strings(packs)
+	return packs
+}
+
+// MissingPacks returns the required packs that aren't in owned. An empty owned
+// list means we never detected anything, so nothing is reported missing.
+func MissingPacks(required, owned []string) []string {
+	if len(owned) == 0 {
+		return nil
+	}
+
+	have := make(map[string]bool)
+	for _, code := range owned {
+		have[code] = true
+	}
+
+	var missing []string
+	for _, code := range required {
+		if !have[code] {
+			missing = append(missing, code)
+		}
+	}
+	return missing
+}
+
+func formatPackList(codes []string) string {
+	labels := make([]string, len(codes))
+	for i, code := range codes {
+		labels[i] = packLabel(code)
+	}
+	return strings.Join(labels, ", ")
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+const patchDayFile = "patchday.json"
+
+const (
+	PatchStatusDisabled        = "disabled"
+	PatchStatusNotFound        = "not on CurseForge"
+	PatchStatusUpdateAvailable = "update available"
+	PatchStatusReenabled       = "re-enabled"
+	PatchStatusUpdated         = "updated"
+)
+
+type PatchDayEntry struct {
+	Script     string   `json:"script"`
+	Companions []string `json:"companions"`
+	ModID      int      `json:"mod_id"`
+	FileID     int      `json:"file_id"`
+	Status     string   `json:"status"`
+}
+
+type PatchDayState struct {
+	Active          bool            `json:"active"`
+	PreviousVersion string          `json:"previous_version"`
+	GameVersion     string          `json:"game_version"`
+	PatchDate       time.Time       `json:"patch_date"`
+	Snapshot        []ModInfo       `json:"snapshot"`
+	Entries         []PatchDayEntry `json:"entries"`
+	LastChecked     time.Time       `json:"last_checked"`
+}
+
+func (e PatchDayEntry) Name() string {
+	return strings.TrimSuffix(filepath.Base(e.Script), disabledSuffix)
+}
+
+func loadPatchDay() (PatchDayState, error) {
+	var state PatchDayState
+
+	data, err := os.ReadFile(patchDayFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, err
+	}
+
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+func savePatchDay(state PatchDayState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(patchDayFile, data, 0644)
+}
+
+// checkForPatch compares the version the game reports with the one we saw last
+// time and offers to start patch day when it changed.
+func checkForPatch(list *widget.List) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return
+	}
+
+	version, patchDate, err := DetectGameVersion(settings.ModsDirectory)
+	if err != nil {
+		// game hasn't been run yet or the documents folder is somewhere else
+		return
+	}
+
+	previous := settings.LastGameVersion
+	if previous == version {
+		return
+	}
+
+	// nothing to compare with on the first run
+	if previous == "" {
+		rememberGameVersion(version)
+		return
+	}
+
+	// the version is only saved once patch day is started, so saying no or
+	// closing the dialog asks again next time and the Start button still knows
+	// what the game was patched from
+
+	fyne.Do(func() {
+		dialog.NewConfirm(
+			"Game Patched",
+			fmt.Sprintf("The game was updated from %s to %s.\nScript mods older than the patch will probably break.\n\nDisable them until compatible versions show up?", previous, version),
+			func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				if _, err := startPatchDay(settings.ModsDirectory, previous, version, patchDate); err != nil {
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				} else {
+					rememberGameVersion(version)
+				}
+				onModsChanged()
+				refreshModsList(list)
+				showPatchDay(list)
+			},
+			fyne.CurrentApp().Driver().AllWindows()[0],
+		).Show()
+	})
+}
+
+func rememberGameVersion(version string) {
+	settings, err := LoadSettings()
+	if err != nil {
+		fmt.Printf("Failed to load settings: %v\n", err)
+		return
+	}
+	settings.LastGameVersion = version
+	if err := SaveSettings(settings); err != nil {
+		fmt.Printf("Failed to save game version: %v\n", err)
+	}
+}
+
+var errPatchDayActive = errors.New("a patch day is already in progress, restore the snapshot to end it first")
+
+// startPatchDay snapshots the installed mods and disables every script mod
+// whose newest file (script or companion package) predates the patch. A patch
+// day in progress isn't replaced, its snapshot is the only way back.
+func startPatchDay(modsDir, previous, version string, patchDate time.Time) (PatchDayState, error) {
+	if IsGameRunning() {
+		return PatchDayState{}, errGameRunning
+	}
+	current, err := loadPatchDay()
+	if err != nil {
+		return PatchDayState{}, err
+	}
+	if current.Active {
+		return PatchDayState{}, errPatchDayActive
+	}
+
+	mods, err := scanMods(modsDir)
+	if err != nil {
+		return PatchDayState{}, err
+	}
+
+	state := PatchDayState{
+		Active:          true,
+		PreviousVersion: previous,
+		GameVersion:     version,
+		PatchDate:       patchDate,
+		Snapshot:        mods,
+	}
+
+	claimed := make(map[string]bool)
+
+	for _, mod := range mods {
+		if mod.Disabled || modExtension(mod.FilePath) != ".ts4script" {
+			continue
+		}
+
+		var companions []ModInfo
+		latest := mod.InstallDate
+		for _, companion := range companionPackages(modsDir, mod, mods) {
+			if claimed[companion.FilePath] {
+				continue
+			}
+			companions = append(companions, companion)
+			if companion.InstallDate.After(latest) {
+				latest = companion.InstallDate
+			}
+		}
+
+		if !latest.Before(patchDate) {
+			continue
+		}
+
+		scriptPath, err := setModEnabled(mod.FilePath, false)
+		if err != nil {
+			savePatchDay(state)
+			return state, fmt.Errorf("failed to disable %s: %w", mod.Name, err)
+		}
+
+		entry := PatchDayEntry{Script: scriptPath, Status: PatchStatusDisabled}
+		for _, companion := range companions {
+			claimed[companion.FilePath] = true
+			path, err := setModEnabled(companion.FilePath, false)
+			if err != nil {
+				state.Entries = append(state.Entries, entry)
+				savePatchDay(state)
+				return state, fmt.Errorf("failed to disable %s: %w", companion.Name, err)
+			}
+			entry.Companions = append(entry.Companions, path)
+		}
+
+		state.Entries = append(state.Entries, entry)
+	}
+
+	return state, savePatchDay(state)
+}
+
+// companionPackages finds the packages that ship with a script mod. In a
+// subfolder that's everything next to the script, in the Mods root it's the
+// packages named like the script.
+func companionPackages(modsDir string, script ModInfo, mods []ModInfo) []ModInfo {
+	var companions []ModInfo
+
+	dir := filepath.Dir(script.FilePath)
+	inRoot := dir == filepath.Clean(modsDir)
+	prefix := strings.ToLower(strings.TrimSuffix(script.Name, ".ts4script"))
+
+	for _, mod := range mods {
+		if mod.Disabled || modExtension(mod.FilePath) != ".package" || filepath.Dir(mod.FilePath) != dir {
+			continue
+		}
+		if inRoot && !strings.HasPrefix(strings.ToLower(mod.Name), prefix) {
+			continue
+		}
+		companions = append(companions, mod)
+	}
+
+	return companions
+}
+
+func enablePatchDayEntry(entry *PatchDayEntry, includeScript bool) error {
+	if includeScript {
+		path, err := setModEnabled(entry.Script, true)
+		if err != nil {
+			return err
+		}
+		entry.Script = path
+	}
+
+	for i, companion := range entry.Companions {
+		path, err := setModEnabled(companion, true)
+		if err != nil {
+			return err
+		}
+		entry.Companions[i] = path
+	}
+
+	return nil
+}
+
+// restorePatchDay puts every mod that was enabled when the snapshot was taken
+// back the way it was and ends patch day.
+func restorePatchDay(state PatchDayState) (PatchDayState, error) {
+	if IsGameRunning() {
+		return state, errGameRunning
+	}
+
+	for _, mod := range state.Snapshot {
+		if mod.Disabled {
+			continue
+		}
+		disabledPath := mod.FilePath + disabledSuffix
+		if _, err := os.Stat(disabledPath); err != nil {
+			continue
+		}
+		if _, err := setModEnabled(disabledPath, true); err != nil {
+			return state, err
+		}
+	}
+
+	state.Active = false
+	return state, savePatchDay(state)
+}
+
+func fileSupportsVersion(file File, version string) bool {
+	for _, gameVersion := range file.GameVersions {
+		if gameVersion == version || strings.HasPrefix(version, gameVersion+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPatchDayUpdates looks the disabled scripts up on CurseForge. Scripts
+// that are tagged for the new version get switched back on, scripts with a
+// file released after the patch get marked for update.
+func checkPatchDayUpdates(client *ApiClient, state PatchDayState) (PatchDayState, error) {
+	// this runs in the background while the window lists the entries, the
+	// results go into a copy the caller swaps in on the UI thread
+	state.Entries = append([]PatchDayEntry(nil), state.Entries...)
+
+	var fingerprints []uint
+	byFingerprint := make(map[uint]int)
+
+	for i, entry := range state.Entries {
+		if entry.Status == PatchStatusReenabled || entry.Status == PatchStatusUpdated {
+			continue
+		}
+
+		fp, err := CalculateFingerprint(entry.Script)
+		if err != nil {
+			return state, fmt.Errorf("error calculating fingerprint for %s: %v", entry.Script, err)
+		}
+
+		fingerprints = append(fingerprints, fp)
+		byFingerprint[fp] = i
+		state.Entries[i].Status = PatchStatusNotFound
+	}
+
+	if len(fingerprints) == 0 {
+		return state, nil
+	}
+
+	resp, err := client.MatchFingerprints(fingerprints)
+	if err != nil {
+		return state, err
+	}
+
+	for _, match := range resp.Data.ExactMatches {
+		i, ok := byFingerprint[uint(match.File.FileFingerprint)]
+		if !ok {
+			continue
+		}
+		entry := &state.Entries[i]
+		entry.ModID = match.ID
+		entry.FileID = match.File.ID
+		entry.Status = PatchStatusDisabled
+
+		if fileSupportsVersion(match.File, state.GameVersion) {
+			if err := enablePatchDayEntry(entry, true); err != nil {
+				return state, err
+			}
+			entry.Status = PatchStatusReenabled
+			continue
+		}
+
+		for _, latest := range match.LatestFiles {
+			if latest.ID == match.File.ID || latest.FileStatus == FileStatusMalware {
+				continue
+			}
+			if latest.FileDate.After(state.PatchDate) || fileSupportsVersion(latest, state.GameVersion) {
+				entry.FileID = latest.ID
+				entry.Status = PatchStatusUpdateAvailable
+				break
+			}
+		}
+	}
+
+	state.LastChecked = time.Now()
+	return state, savePatchDay(state)
+}
+
+func showPatchDay(list *widget.List) {
+	state, err := loadPatchDay()
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+
+	patchWindow := fyne.CurrentApp().NewWindow("Patch Day")
+	patchWindow.Resize(fyne.NewSize(700, 450))
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+
+	var entriesList *widget.List
+	var updateStatus func()
+
+	save := func() {
+		if err := savePatchDay(state); err != nil {
+			dialog.ShowError(err, patchWindow)
+		}
+		onModsChanged()
+		updateStatus()
+		entriesList.Refresh()
+		refreshModsList(list)
+	}
+
+	entriesList = widget.NewList(
+		func() int { return len(state.Entries) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(
+				nil, nil, nil,
+				container.NewHBox(widget.NewButton("Enable", func() {}), widget.NewButton("Update", func() {})),
+				container.NewVBox(widget.NewLabel("Script"), widget.NewLabel("Status")),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			entry := &state.Entries[id]
+			row := item.(*fyne.Container)
+
+			labels := row.Objects[0].(*fyne.Container)
+			labels.Objects[0].(*widget.Label).SetText(entry.Name())
+			statusText := entry.Status
+			if len(entry.Companions) > 0 {
+				statusText += fmt.Sprintf(" (%d companion packages)", len(entry.Companions))
+			}
+			labels.Objects[1].(*widget.Label).SetText(statusText)
+
+			buttons := row.Objects[1].(*fyne.Container)
+
+			enableButton := buttons.Objects[0].(*widget.Button)
+			enableButton.OnTapped = func() {
+				if err := enablePatchDayEntry(entry, true); err != nil {
+					dialog.ShowError(err, patchWindow)
+					return
+				}
+				entry.Status = PatchStatusReenabled
+				save()
+			}
+
+			updateButton := buttons.Objects[1].(*widget.Button)
+			if entry.Status == PatchStatusUpdateAvailable {
+				updateButton.Enable()
+			} else {
+				updateButton.Disable()
+			}
+			updateButton.OnTapped = func() {
+				modID, fileID := entry.ModID, entry.FileID
+				go func() {
+					modResp, err := apiClient.GetMod(modID)
+					if err != nil {
+						fyne.Do(func() { dialog.ShowError(err, patchWindow) })
+						return
+					}
+					filesResp, err := apiClient.GetFilesByIds([]int{fileID})
+					if err != nil || len(filesResp.Data) == 0 {
+						fyne.Do(func() { dialog.ShowError(fmt.Errorf("couldn't find file %d: %v", fileID, err), patchWindow) })
+						return
+					}
+
+					fyne.Do(func() {
+						// the old script stays disabled, the new file replaces it
+						if err := enablePatchDayEntry(entry, false); err != nil {
+							dialog.ShowError(err, patchWindow)
+							return
+						}
+						entry.Status = PatchStatusUpdated
+						save()
+						downloadFile(modResp.Data, filesResp.Data[0])
+					})
+				}()
+			}
+		},
+	)
+
+	updateStatus = func() {
+		if len(state.Entries) == 0 && !state.Active {
+			statusLabel.SetText("No patch day in progress.")
+			return
+		}
+		pending := 0
+		for _, entry := range state.Entries {
+			if entry.Status != PatchStatusReenabled && entry.Status != PatchStatusUpdated {
+				pending++
+			}
+		}
+		text := fmt.Sprintf("Patch %s -> %s on %s. %d of %d script mods still waiting.",
+			state.PreviousVersion, state.GameVersion, state.PatchDate.Format("2006-01-02"), pending, len(state.Entries))
+		if !state.LastChecked.IsZero() {
+			text += "\nLast checked CurseForge: " + state.LastChecked.Format("2006-01-02 15:04:05")
+		}
+		if !state.Active {
+			text += "\nPatch day has ended."
+		}
+		statusLabel.SetText(text)
+	}
+	updateStatus()
+
+	checkButton := widget.NewButton("Check CurseForge", func() {
+		if apiClient == nil {
+			dialog.ShowError(fmt.Errorf("set up your CurseForge API key in the Browse tab first"), patchWindow)
+			return
+		}
+		progress := dialog.NewProgressInfinite("Patch Day", "Checking for compatible versions...", patchWindow)
+		progress.Show()
+		go func() {
+			newState, err := checkPatchDayUpdates(apiClient, state)
+			fyne.Do(func() {
+				progress.Hide()
+				state = newState
+				if err != nil {
+					dialog.ShowError(err, patchWindow)
+				}
+				save()
+			})
+		}()
+	})
+
+	restoreButton := widget.NewButton("Restore Snapshot", func() {
+		dialog.NewConfirm("Restore Snapshot", "Re-enable everything that was enabled before the patch?", func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			newState, err := restorePatchDay(state)
+			state = newState
+			if err != nil {
+				dialog.ShowError(err, patchWindow)
+			}
+			save()
+		}, patchWindow).Show()
+	})
+
+	startButton := widget.NewButton("Start Patch Day", func() {
+		if state.Active {
+			dialog.ShowError(errPatchDayActive, patchWindow)
+			return
+		}
+		settings, err := LoadSettings()
+		if err != nil {
+			dialog.ShowError(err, patchWindow)
+			return
+		}
+		version, patchDate, err := DetectGameVersion(settings.ModsDirectory)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("can't detect the game version: %w", err), patchWindow)
+			return
+		}
+		previous := settings.LastGameVersion
+		if previous == version && state.GameVersion == version {
+			// patch day for this version was started before, compare with the same version again
+			previous = state.PreviousVersion
+		}
+		newState, err := startPatchDay(settings.ModsDirectory, previous, version, patchDate)
+		if err != nil {
+			// one that failed partway is saved already and has scripts disabled,
+			// anything else left the current state alone
+			if newState.Active {
+				state = newState
+				save()
+			}
+			dialog.ShowError(err, patchWindow)
+			return
+		}
+		state = newState
+		rememberGameVersion(version)
+		save()
+	})
+
+	patchWindow.SetContent(container.NewBorder(
+		statusLabel,
+		container.NewHBox(startButton, layout.NewSpacer(), restoreButton, checkButton),
+		nil, nil,
+		entriesList,
+	))
+	patchWindow.Show()
+}
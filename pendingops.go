@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const pendingOpsFile = "pending_ops.json"
+
+// new files are written here first so they can be scanned before they reach
+// the Mods folder, and wait here while the game is running
+const stagingDir = "staging"
+
+const (
+	PendingMove   = "move"
+	PendingRename = "rename"
+	PendingDelete = "delete"
+)
+
+// PendingOp is a Mods folder change we couldn't make because the game was
+// running. They're applied in order once it exits.
+type PendingOp struct {
+	Kind        string    `json:"kind"`
+	Source      string    `json:"source"`
+	Target      string    `json:"target"`
+	Description string    `json:"description"`
+	Queued      time.Time `json:"queued"`
+	// journal group for ops that were one action, 0 for none
+	Group int64 `json:"group,omitempty"`
+}
+
+var pendingOpsMu sync.Mutex
+
+func loadPendingOps() ([]PendingOp, error) {
+	var ops []PendingOp
+
+	data, err := os.ReadFile(pendingOpsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ops, nil
+		}
+		return ops, err
+	}
+
+	err = json.Unmarshal(data, &ops)
+	return ops, err
+}
+
+func savePendingOps(ops []PendingOp) error {
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(pendingOpsFile, data, 0644)
+}
+
+func queuePendingOp(op PendingOp) error {
+	pendingOpsMu.Lock()
+	defer pendingOpsMu.Unlock()
+
+	ops, err := loadPendingOps()
+	if err != nil {
+		return err
+	}
+
+	op.Queued = time.Now()
+	return savePendingOps(append(ops, op))
+}
+
+func pendingOpsCount() int {
+	pendingOpsMu.Lock()
+	defer pendingOpsMu.Unlock()
+
+	ops, _ := loadPendingOps()
+	return len(ops)
+}
+
+// stagingPath returns where a new file for targetPath should be written
+// before admitModFile decides whether it may go to targetPath.
+func stagingPath(targetPath string) (string, error) {
+	if err := ensureDirectoryExists(stagingDir); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(stagingDir, strconv.FormatInt(time.Now().UnixNano(), 10)+"-"+filepath.Base(targetPath)), nil
+}
+
+func queueStagedMove(staged, targetPath string) error {
+	return queuePendingOp(PendingOp{
+		Kind:        PendingMove,
+		Source:      staged,
+		Target:      targetPath,
+		Description: "install " + filepath.Base(targetPath),
+	})
+}
+
+// moveFile renames source to target, copying when they're on different
+// filesystems (staging and quarantine might not be next to the Mods folder).
+func moveFile(source, target string) error {
+	// the Mods watcher shouldn't take this for a change made by hand
+	noteOwnChange(source, target)
+	defer noteOwnChange(source, target)
+
+	if err := renameOrCopy(source, target); err != nil {
+		return err
+	}
+	carryUntracked(source, target)
+	return nil
+}
+
+func renameOrCopy(source, target string) error {
+	if err := ensureDirectoryExists(filepath.Dir(target)); err != nil {
+		return err
+	}
+	err := os.Rename(source, target)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	// the trash or the staging folder can be on another filesystem than Mods
+	if err := copyFile(source, target); err != nil {
+		return err
+	}
+	return os.Remove(source)
+}
+
+// copyFile streams source to target and keeps its permissions and
+// modification time, the scan index goes by the time. A failed copy doesn't
+// leave half a file behind.
+func copyFile(source, target string) (err error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s isn't a regular file", filepath.Base(source))
+	}
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(target)
+		}
+	}()
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
+
+func applyPendingOp(op PendingOp) error {
+	switch op.Kind {
+	case PendingMove:
+		return journaledInstall(op.Source, op.Target)
+	case PendingRename:
+		return journaledMove(op.Group, op.Source, op.Target)
+	case PendingDelete:
+		if _, err := os.Lstat(op.Target); os.IsNotExist(err) {
+			return nil
+		}
+		return journaledDelete(op.Group, op.Target)
+	}
+	return fmt.Errorf("unknown queued operation %q", op.Kind)
+}
+
+// applyQueuedChanges applies whatever was queued while the game ran, unless
+// it's still running. The GUI and the command line call it on startup since
+// the manager may have been closed before the game exited.
+func applyQueuedChanges() {
+	if IsGameRunning() || pendingOpsCount() == 0 {
+		return
+	}
+	applied, err := applyPendingOps()
+	if err != nil {
+		fmt.Printf("Failed to apply queued changes: %v\n", err)
+	}
+	if len(applied) > 0 {
+		fmt.Printf("Applied %d queued changes to the Mods folder\n", len(applied))
+		onModsChanged()
+	}
+}
+
+// applyPendingOps runs the queue. Anything that fails stays queued so it can
+// be retried, the rest is dropped.
+func applyPendingOps() ([]PendingOp, error) {
+	pendingOpsMu.Lock()
+	defer pendingOpsMu.Unlock()
+
+	if IsGameRunning() {
+		return nil, errGameRunning
+	}
+
+	ops, err := loadPendingOps()
+	if err != nil || len(ops) == 0 {
+		return nil, err
+	}
+
+	var applied, failed []PendingOp
+	var firstErr error
+	for _, op := range ops {
+		if err := applyPendingOp(op); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", op.Description, err)
+			}
+			failed = append(failed, op)
+			continue
+		}
+		applied = append(applied, op)
+	}
+
+	if err := savePendingOps(failed); err != nil {
+		return applied, err
+	}
+	return applied, firstErr
+}
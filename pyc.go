@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// A .pyc is a 16 byte header and a marshalled code object. The scanner only
+// needs the import names, the names tables and the string constants out of
+// it, but marshal has no lengths on its containers, so everything gets read.
+
+var errPycVersion = errors.New("not bytecode for a Python the scanner knows")
+
+// the code object layout changed in 3.8 (posonlyargcount) and again in 3.11
+// (locals merged into localsplusnames), the opcodes got renumbered in 3.13
+type pycLayout struct {
+	intFields   int
+	layout311   bool
+	importName  byte
+	extendedArg byte
+}
+
+func pycLayoutFor(magic uint16) (pycLayout, error) {
+	switch {
+	case magic >= 3390 && magic < 3400: // 3.7, what the game ships
+		return pycLayout{intFields: 5, importName: 108, extendedArg: 144}, nil
+	case magic >= 3400 && magic < 3450: // 3.8 - 3.10
+		return pycLayout{intFields: 6, importName: 108, extendedArg: 144}, nil
+	case magic >= 3450 && magic < 3550: // 3.11, 3.12
+		return pycLayout{intFields: 5, layout311: true, importName: 108, extendedArg: 144}, nil
+	case magic >= 3550 && magic < 3600: // 3.13
+		return pycLayout{intFields: 5, layout311: true, importName: 75, extendedArg: 71}, nil
+	}
+	return pycLayout{}, errPycVersion
+}
+
+// what the scanner gets out of a .pyc, over every code object in it
+type pycContents struct {
+	imports []string // modules named by import statements
+	names   []string // co_names, globals and attributes the code uses
+	strings []string // string constants, co_filename and friends left out
+}
+
+type pycCode struct {
+	code   []byte
+	consts interface{}
+	names  []string
+}
+
+type pycTuple struct {
+	items []interface{}
+}
+
+// real bytecode nests a few levels per nested function or class, Python
+// itself gives up at 2000
+const pycMaxDepth = 500
+
+type pycReader struct {
+	data   []byte
+	pos    int
+	layout pycLayout
+	refs   []interface{}
+	codes  []*pycCode
+	depth  int
+}
+
+func readPyc(data []byte) (pycContents, error) {
+	var contents pycContents
+	if len(data) < 16 || data[2] != '\r' || data[3] != '\n' {
+		return contents, errPycVersion
+	}
+	layout, err := pycLayoutFor(binary.LittleEndian.Uint16(data))
+	if err != nil {
+		return contents, err
+	}
+
+	r := &pycReader{data: data, pos: 16, layout: layout}
+	top, err := r.object()
+	if err != nil {
+		return contents, err
+	}
+	if _, ok := top.(*pycCode); !ok {
+		return contents, errors.New("no code object in the bytecode")
+	}
+
+	seen := make(map[*pycTuple]bool)
+	for _, code := range r.codes {
+		contents.names = append(contents.names, code.names...)
+		contents.imports = append(contents.imports, code.importNames(layout)...)
+		contents.strings = appendConstStrings(contents.strings, code.consts, seen)
+	}
+	return contents, nil
+}
+
+// importNames decodes the wordcode for IMPORT_NAME, its argument is the index
+// of the module in co_names.
+func (c *pycCode) importNames(layout pycLayout) []string {
+	var modules []string
+	arg := 0
+	for i := 0; i+1 < len(c.code); i += 2 {
+		op := c.code[i]
+		arg = arg<<8 | int(c.code[i+1])
+		switch op {
+		case layout.extendedArg:
+			continue
+		case layout.importName:
+			if arg < len(c.names) {
+				modules = append(modules, c.names[arg])
+			}
+		}
+		arg = 0
+	}
+	return modules
+}
+
+// appendConstStrings collects the strings from co_consts, tuples included.
+// Nested code objects are in r.codes already.
+func appendConstStrings(out []string, value interface{}, seen map[*pycTuple]bool) []string {
+	switch v := value.(type) {
+	case string:
+		out = append(out, v)
+	case []byte:
+		out = append(out, string(v))
+	case *pycTuple:
+		if seen[v] {
+			return out
+		}
+		seen[v] = true
+		for _, item := range v.items {
+			out = appendConstStrings(out, item, seen)
+		}
+	}
+	return out
+}
+
+func (r *pycReader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.pos {
+		return nil, errors.New("bytecode is cut short")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *pycReader) uint8() (int, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return int(b[0]), nil
+}
+
+func (r *pycReader) int32() (int, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(int32(binary.LittleEndian.Uint32(b))), nil
+}
+
+// count reads a container length, every item takes at least a byte so
+// anything longer than what's left is garbage
+func (r *pycReader) count() (int, error) {
+	n, err := r.int32()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > len(r.data)-r.pos {
+		return 0, fmt.Errorf("bad length %d in bytecode", n)
+	}
+	return n, nil
+}
+
+func (r *pycReader) object() (interface{}, error) {
+	if r.depth >= pycMaxDepth {
+		return nil, errors.New("bytecode nests too deep")
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+
+	kind, err := r.uint8()
+	if err != nil {
+		return nil, err
+	}
+
+	// flagged objects get a slot that later 'r' entries point back to. The
+	// slot is taken before the children are read, like marshal does.
+	ref := -1
+	if kind&0x80 != 0 {
+		kind &^= 0x80
+		ref = len(r.refs)
+		r.refs = append(r.refs, nil)
+	}
+
+	value, err := r.value(byte(kind))
+	if err != nil {
+		return nil, err
+	}
+	if ref >= 0 {
+		r.refs[ref] = value
+	}
+	return value, nil
+}
+
+func (r *pycReader) value(kind byte) (interface{}, error) {
+	switch kind {
+	case '0', 'N', 'F', 'T', '.', 'S':
+		return nil, nil
+	case 'i':
+		_, err := r.bytes(4)
+		return nil, err
+	case 'I', 'g':
+		_, err := r.bytes(8)
+		return nil, err
+	case 'y':
+		_, err := r.bytes(16)
+		return nil, err
+	case 'l':
+		n, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			n = -n
+		}
+		_, err = r.bytes(2 * n)
+		return nil, err
+	case 'f':
+		n, err := r.uint8()
+		if err != nil {
+			return nil, err
+		}
+		_, err = r.bytes(n)
+		return nil, err
+	case 'x':
+		for i := 0; i < 2; i++ {
+			n, err := r.uint8()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := r.bytes(n); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case 's':
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		return r.bytes(n)
+	case 't', 'u', 'a', 'A':
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.bytes(n)
+		return string(b), err
+	case 'z', 'Z':
+		n, err := r.uint8()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.bytes(n)
+		return string(b), err
+	case ')':
+		n, err := r.uint8()
+		if err != nil {
+			return nil, err
+		}
+		return r.items(n)
+	case '(', '[', '<', '>':
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		return r.items(n)
+	case '{':
+		for {
+			key, err := r.dictEnd()
+			if err != nil {
+				return nil, err
+			}
+			if key {
+				return nil, nil
+			}
+			if _, err := r.object(); err != nil {
+				return nil, err
+			}
+			if _, err := r.object(); err != nil {
+				return nil, err
+			}
+		}
+	case 'r':
+		n, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || n >= len(r.refs) {
+			return nil, fmt.Errorf("bad reference %d in bytecode", n)
+		}
+		return r.refs[n], nil
+	case 'c':
+		return r.code()
+	}
+	return nil, fmt.Errorf("unknown type %q in bytecode", kind)
+}
+
+// dictEnd eats the '0' that ends a dict
+func (r *pycReader) dictEnd() (bool, error) {
+	if r.pos >= len(r.data) {
+		return false, errors.New("bytecode is cut short")
+	}
+	if r.data[r.pos] == '0' {
+		r.pos++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *pycReader) items(n int) (*pycTuple, error) {
+	tuple := &pycTuple{items: make([]interface{}, 0, n)}
+	for i := 0; i < n; i++ {
+		item, err := r.object()
+		if err != nil {
+			return nil, err
+		}
+		tuple.items = append(tuple.items, item)
+	}
+	return tuple, nil
+}
+
+func (r *pycReader) code() (*pycCode, error) {
+	for i := 0; i < r.layout.intFields; i++ {
+		if _, err := r.int32(); err != nil {
+			return nil, err
+		}
+	}
+
+	code := &pycCode{}
+	bytecode, err := r.object()
+	if err != nil {
+		return nil, err
+	}
+	code.code, _ = bytecode.([]byte)
+	if code.consts, err = r.object(); err != nil {
+		return nil, err
+	}
+	names, err := r.object()
+	if err != nil {
+		return nil, err
+	}
+	if tuple, ok := names.(*pycTuple); ok {
+		for _, item := range tuple.items {
+			if name, ok := item.(string); ok {
+				code.names = append(code.names, name)
+			}
+		}
+	}
+
+	// the rest is locals, filename, name and line tables, none of which say
+	// anything about what the code does
+	// varnames, freevars, cellvars, filename, name, or from 3.11
+	// localsplusnames, localspluskinds, filename, name, qualname
+	for i := 0; i < 5; i++ {
+		if _, err := r.object(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.int32(); err != nil { // firstlineno
+		return nil, err
+	}
+	tables := 1 // lnotab
+	if r.layout.layout311 {
+		tables = 2 // linetable, exceptiontable
+	}
+	for i := 0; i < tables; i++ {
+		if _, err := r.object(); err != nil {
+			return nil, err
+		}
+	}
+
+	r.codes = append(r.codes, code)
+	return code, nil
+}
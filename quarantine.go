@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// suspicious downloads and installs end up here instead of in Mods, one
+// folder per file with the report next to it
+const quarantineDir = "quarantine"
+
+const quarantineReportFile = "report.json"
+
+const (
+	AdmitInstalled   = "installed"
+	AdmitQueued      = "queued"
+	AdmitQuarantined = "quarantined"
+)
+
+type QuarantineEntry struct {
+	Dir      string          `json:"-"`
+	Name     string          `json:"name"`
+	Target   string          `json:"target"`
+	Reason   string          `json:"reason"`
+	Findings []ScriptFinding `json:"findings"`
+	Date     time.Time       `json:"date"`
+}
+
+func (e QuarantineEntry) FilePath() string {
+	return filepath.Join(e.Dir, e.Name)
+}
+
+func (e QuarantineEntry) Report() string {
+	report := fmt.Sprintf("%s\nQuarantined %s\nReason: %s\nWould have been installed to: %s\n",
+		e.Name, e.Date.Format("2006-01-02 15:04"), e.Reason, e.Target)
+	if len(e.Findings) > 0 {
+		report += "\nFindings:\n" + formatFindings(e.Findings)
+	}
+	return report
+}
+
+// fileStatusReason turns the CurseForge moderation status into a reason to
+// quarantine, or "" when the file is fine.
+func fileStatusReason(status int) string {
+	switch status {
+	case FileStatusMalware:
+		return "CurseForge flagged this file as malware"
+	case FileStatusRejected:
+		return "CurseForge rejected this file in moderation"
+	}
+	return ""
+}
+
+func quarantineFile(source, target, reason string, findings []ScriptFinding) (QuarantineEntry, error) {
+	name := filepath.Base(target)
+	entry := QuarantineEntry{
+		Dir:      filepath.Join(quarantineDir, strconv.FormatInt(time.Now().UnixNano(), 10)+"-"+name),
+		Name:     name,
+		Target:   target,
+		Reason:   reason,
+		Findings: findings,
+		Date:     time.Now(),
+	}
+
+	if err := moveFile(source, entry.FilePath()); err != nil {
+		return entry, fmt.Errorf("failed to quarantine %s: %w", name, err)
+	}
+	// nothing in here should be runnable
+	os.Chmod(entry.FilePath(), 0600)
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return entry, err
+	}
+	if err := os.WriteFile(filepath.Join(entry.Dir, quarantineReportFile), data, 0644); err != nil {
+		return entry, err
+	}
+
+	fmt.Printf("Quarantined %s: %s\n", name, reason)
+	return entry, nil
+}
+
+func ListQuarantine() ([]QuarantineEntry, error) {
+	dirs, err := os.ReadDir(quarantineDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []QuarantineEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		path := filepath.Join(quarantineDir, dir.Name())
+
+		data, err := os.ReadFile(filepath.Join(path, quarantineReportFile))
+		if err != nil {
+			continue
+		}
+		var entry QuarantineEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entry.Dir = path
+
+		// released while the game was running, the queued move took the file
+		if _, err := os.Stat(entry.FilePath()); os.IsNotExist(err) {
+			os.RemoveAll(path)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Date.After(entries[j].Date) })
+	return entries, nil
+}
+
+// ReleaseQuarantined installs a quarantined file where it was headed after
+// all. Returns true when it had to be queued because the game is running.
+func ReleaseQuarantined(entry QuarantineEntry) (bool, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return false, err
+	}
+	if err := checkModsTarget(settings.ModsDirectory, entry.Target); err != nil {
+		return false, err
+	}
+
+	os.Chmod(entry.FilePath(), 0644)
+
+	if IsGameRunning() {
+		return true, queueStagedMove(entry.FilePath(), entry.Target)
+	}
+
+	if err := journaledInstall(entry.FilePath(), entry.Target); err != nil {
+		return false, err
+	}
+	onModsChanged()
+	return false, os.RemoveAll(entry.Dir)
+}
+
+func DeleteQuarantined(entry QuarantineEntry) error {
+	return os.RemoveAll(entry.Dir)
+}
+
+// admitModFile is the only way new files get into the Mods folder. The target
+// goes through checkModsTarget and the file at incoming is checked against the
+// CurseForge status and the script scanner, then installed to target, queued
+// for when the game exits or quarantined.
+func admitModFile(incoming, modsDir, target string, fileStatus int) (string, QuarantineEntry, error) {
+	if err := checkModsTarget(modsDir, target); err != nil {
+		os.Remove(incoming)
+		return "", QuarantineEntry{}, err
+	}
+
+	reason := fileStatusReason(fileStatus)
+
+	findings, err := ScanScriptForMalware(incoming)
+	if err != nil && reason == "" {
+		reason = "script archive can't be read: " + err.Error()
+	}
+	if len(findings) > 0 && reason == "" {
+		reason = "script mod does things mods normally don't"
+	}
+
+	if reason != "" {
+		entry, err := quarantineFile(incoming, target, reason, findings)
+		return AdmitQuarantined, entry, err
+	}
+
+	outcome := AdmitInstalled
+	if IsGameRunning() {
+		if err := queueStagedMove(incoming, target); err != nil {
+			return "", QuarantineEntry{}, err
+		}
+		outcome = AdmitQueued
+	} else if err := journaledInstall(incoming, target); err != nil {
+		return "", QuarantineEntry{}, err
+	}
+
+	// files picked by hand get an entry too, downloads fill in the mod after
+	if err := recordInstall(ManifestEntry{Path: target}); err != nil {
+		fmt.Printf("Failed to update manifest: %v\n", err)
+	}
+	return outcome, QuarantineEntry{}, nil
+}
+
+// ScanInstalledScripts runs the scanner over the script mods already in the
+// Mods folder, for anything installed before the scanner existed or by hand.
+func ScanInstalledScripts(modsDir string) (map[string][]ScriptFinding, error) {
+	archives, err := ScanScriptArchives(modsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	flagged := make(map[string][]ScriptFinding)
+	for _, archive := range archives {
+		findings, err := ScanScriptForMalware(archive.Path)
+		if err != nil {
+			continue
+		}
+		if len(findings) > 0 {
+			flagged[archive.Path] = findings
+		}
+	}
+	return flagged, nil
+}
+
+func quarantineInstalled(path string, findings []ScriptFinding) error {
+	if IsGameRunning() {
+		return errGameRunning
+	}
+	entry, err := quarantineFile(path, path, "installed script mod does things mods normally don't", findings)
+	if err != nil {
+		return err
+	}
+	logFileOp(JournalEntry{Op: JournalMove, From: path, Path: entry.FilePath()})
+	onModsChanged()
+	return nil
+}
+
+func summarizeFindings(findings []ScriptFinding) string {
+	seen := make(map[string]bool)
+	var capabilities []string
+	for _, finding := range findings {
+		if !seen[finding.Capability] {
+			seen[finding.Capability] = true
+			capabilities = append(capabilities, finding.Capability)
+		}
+	}
+	return strings.Join(capabilities, ", ")
+}
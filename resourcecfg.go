@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// what the game ships with: packages up to five folders deep
+const defaultResourceCfg = "Priority 500\r\n" +
+	"PackedFile *.package\r\n" +
+	"PackedFile */*.package\r\n" +
+	"PackedFile */*/*.package\r\n" +
+	"PackedFile */*/*/*.package\r\n" +
+	"PackedFile */*/*/*/*.package\r\n" +
+	"PackedFile */*/*/*/*/*.package\r\n"
+
+type ResourceCfg struct {
+	Priority int
+	// folder depths the game scans for packages, 0 is the Mods root
+	Depths   map[int]bool
+	Problems []string
+}
+
+func resourceCfgPath(modsDir string) string {
+	return filepath.Join(modsDir, "Resource.cfg")
+}
+
+func (c ResourceCfg) MaxDepth() int {
+	max := -1
+	for depth := range c.Depths {
+		if depth > max {
+			max = depth
+		}
+	}
+	return max
+}
+
+// ParseResourceCfg reads Resource.cfg. Lines the game wouldn't understand end
+// up in Problems instead of failing the whole parse.
+func ParseResourceCfg(modsDir string) (ResourceCfg, error) {
+	cfg := ResourceCfg{Depths: make(map[int]bool)}
+
+	file, err := os.Open(resourceCfgPath(modsDir))
+	if err != nil {
+		return cfg, err
+	}
+	defer file.Close()
+
+	lineNum := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch strings.ToLower(fields[0]) {
+		case "priority":
+			if len(fields) != 2 {
+				cfg.Problems = append(cfg.Problems, fmt.Sprintf("line %d: Priority needs one number", lineNum))
+				continue
+			}
+			priority, err := strconv.Atoi(fields[1])
+			if err != nil {
+				cfg.Problems = append(cfg.Problems, fmt.Sprintf("line %d: bad priority %q", lineNum, fields[1]))
+				continue
+			}
+			cfg.Priority = priority
+		case "packedfile":
+			if len(fields) != 2 {
+				cfg.Problems = append(cfg.Problems, fmt.Sprintf("line %d: PackedFile needs one pattern", lineNum))
+				continue
+			}
+			depth, ok := patternDepth(fields[1])
+			if !ok {
+				cfg.Problems = append(cfg.Problems, fmt.Sprintf("line %d: pattern %q doesn't match packages by folder depth", lineNum, fields[1]))
+				continue
+			}
+			cfg.Depths[depth] = true
+		case "directoryfiles":
+			// used by some old setups for extracted resources, the game still accepts it
+		default:
+			cfg.Problems = append(cfg.Problems, fmt.Sprintf("line %d: unknown directive %q", lineNum, fields[0]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Priority == 0 {
+		cfg.Problems = append(cfg.Problems, "no Priority line")
+	}
+	if len(cfg.Depths) == 0 {
+		cfg.Problems = append(cfg.Problems, "no PackedFile lines, the game won't load any packages")
+	}
+	for depth := 0; depth < cfg.MaxDepth(); depth++ {
+		if !cfg.Depths[depth] {
+			cfg.Problems = append(cfg.Problems, fmt.Sprintf("packages %d folders deep are skipped even though deeper ones load", depth))
+		}
+	}
+
+	return cfg, nil
+}
+
+// patternDepth turns "*/*/*.package" into 2. Anything other than plain
+// wildcard folders is something we can't reason about.
+func patternDepth(pattern string) (int, bool) {
+	parts := strings.Split(strings.ReplaceAll(pattern, `\`, "/"), "/")
+	if !strings.EqualFold(parts[len(parts)-1], "*.package") {
+		return 0, false
+	}
+	for _, part := range parts[:len(parts)-1] {
+		if part != "*" {
+			return 0, false
+		}
+	}
+	return len(parts) - 1, true
+}
+
+func packageDepth(modsDir, path string) int {
+	rel, err := filepath.Rel(modsDir, path)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(filepath.ToSlash(rel), "/")
+}
+
+// UnreachablePackages lists the packages the game won't load because they sit
+// at a depth Resource.cfg doesn't cover.
+func UnreachablePackages(modsDir string, cfg ResourceCfg) ([]string, error) {
+	var unreachable []string
+
+	err := filepath.Walk(modsDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".package" {
+			return nil
+		}
+		if !cfg.Depths[packageDepth(modsDir, path)] {
+			unreachable = append(unreachable, path)
+		}
+		return nil
+	})
+
+	sort.Strings(unreachable)
+	return unreachable, err
+}
+
+// WriteDefaultResourceCfg replaces Resource.cfg with the one the game ships,
+// keeping the old one next to it.
+func WriteDefaultResourceCfg(modsDir string) error {
+	path := resourceCfgPath(modsDir)
+
+	if old, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", old, 0644); err != nil {
+			return fmt.Errorf("failed to back up Resource.cfg: %w", err)
+		}
+	}
+
+	return os.WriteFile(path, []byte(defaultResourceCfg), 0644)
+}
+
+// packageInstallDir picks where a new package goes: the Mods root if the
+// config loads packages there, otherwise a folder named after the mod at the
+// shallowest depth that loads.
+func packageInstallDir(modsDir, modName string) (string, error) {
+	cfg, err := ParseResourceCfg(modsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return modsDir, nil
+		}
+		return "", err
+	}
+
+	if cfg.Depths[0] {
+		return modsDir, nil
+	}
+	if cfg.Depths[1] {
+		return filepath.Join(modsDir, modName), nil
+	}
+	return "", fmt.Errorf("Resource.cfg doesn't load packages from the Mods folder or one folder down, regenerate it first")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the game runs under Proton/Wine, so names have to be valid on Windows too
+const windowsInvalidChars = `<>:"|?*`
+
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true,
+	"LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// validateModFileName checks a single path component, a file name from the
+// API, a picked file or an archive entry.
+func validateModFileName(name string) error {
+	switch {
+	case name == "" || name == "." || name == "..":
+		return fmt.Errorf("unsafe file name %q", name)
+	case strings.ContainsAny(name, `/\`):
+		return fmt.Errorf("unsafe file name %q: contains a path", name)
+	case len(name) > 255:
+		return fmt.Errorf("unsafe file name %q: too long", name)
+	case strings.HasSuffix(name, ".") || strings.HasSuffix(name, " "):
+		// windows drops these, so the game would see a different name
+		return fmt.Errorf("unsafe file name %q: ends in a dot or space", name)
+	}
+
+	for _, c := range name {
+		if c < 0x20 || strings.ContainsRune(windowsInvalidChars, c) {
+			return fmt.Errorf("unsafe file name %q: %q isn't allowed on Windows", name, c)
+		}
+	}
+
+	base := strings.ToUpper(strings.TrimSpace(strings.SplitN(name, ".", 2)[0]))
+	if windowsReservedNames[base] {
+		return fmt.Errorf("unsafe file name %q: %s is a reserved name on Windows", name, base)
+	}
+	return nil
+}
+
+// checkCaseCollision fails when dir already has name with different case.
+// Linux keeps both but Wine only ever opens one of them.
+func checkCaseCollision(dir, name string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() != name && strings.EqualFold(entry.Name(), name) {
+			return fmt.Errorf("%s clashes with %s, the game can't tell them apart", name, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// checkModsTarget is the last check before anything is written into the Mods
+// folder. The target has to be inside it, every component a safe name, not
+// colliding by case with what's there and not going through a symlink.
+func checkModsTarget(modsDir, target string) error {
+	modsDir = filepath.Clean(modsDir)
+	rel, err := filepath.Rel(modsDir, filepath.Clean(target))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside the Mods folder", target)
+	}
+
+	dir := modsDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if err := validateModFileName(part); err != nil {
+			return err
+		}
+		if err := checkCaseCollision(dir, part); err != nil {
+			return err
+		}
+
+		dir = filepath.Join(dir, part)
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink, not writing through it", dir)
+		}
+	}
+	return nil
+}
+
+// SafeModPath turns an untrusted relative path (API file name, archive entry,
+// "folder/file.package") into a path inside the Mods folder. Components are
+// checked before joining so "../" and absolute paths can't sneak out.
+func SafeModPath(modsDir, rel string) (string, error) {
+	parts := strings.FieldsFunc(rel, func(c rune) bool { return c == '/' || c == '\\' })
+	if len(parts) == 0 || strings.HasPrefix(rel, "/") || strings.HasPrefix(rel, `\`) {
+		return "", fmt.Errorf("unsafe path %q", rel)
+	}
+
+	for _, part := range parts {
+		if err := validateModFileName(part); err != nil {
+			return "", err
+		}
+	}
+
+	target := filepath.Join(append([]string{modsDir}, parts...)...)
+	if err := checkModsTarget(modsDir, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// modInstallPath works out where a downloaded or picked file goes, packages
+// into their own folder when Resource.cfg allows it, and creates the folder.
+func modInstallPath(modsDir, fileName string) (string, error) {
+	if err := validateModFileName(fileName); err != nil {
+		return "", err
+	}
+
+	rel := fileName
+	if filepath.Ext(fileName) == ".package" {
+		dir, err := packageInstallDir(modsDir, strings.TrimSuffix(fileName, ".package"))
+		if err != nil {
+			return "", err
+		}
+		if dir != modsDir {
+			rel = filepath.Base(dir) + "/" + fileName
+		}
+	}
+
+	target, err := SafeModPath(modsDir, rel)
+	if err != nil {
+		return "", err
+	}
+	if err := ensureDirectoryExists(filepath.Dir(target)); err != nil {
+		return "", err
+	}
+	return target, nil
+}
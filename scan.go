@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scanWorkers bounds how many files are read at once. Reading packages is
+// mostly waiting on the disk, so a few more than the CPUs still helps.
+var scanWorkers = min(runtime.NumCPU()*2, 16)
+
+// ScanProgress is what the Mods tab shows while a scan runs. Total is zero
+// while it isn't known yet, like during the walk.
+type ScanProgress struct {
+	Stage string
+	Done  int
+	Total int
+}
+
+type ProgressFunc func(ScanProgress)
+
+// progressInterval keeps a 20k file scan from flooding the UI with updates
+const progressInterval = 100 * time.Millisecond
+
+// progressReporter counts finished work from any goroutine and passes it on
+// to a ProgressFunc now and then. A nil ProgressFunc is fine.
+type progressReporter struct {
+	mu     sync.Mutex
+	report ProgressFunc
+	stage  string
+	done   int
+	total  int
+	last   time.Time
+}
+
+func newProgressReporter(report ProgressFunc, stage string, total int) *progressReporter {
+	p := &progressReporter{report: report, stage: stage, total: total}
+	p.send()
+	return p
+}
+
+func (p *progressReporter) add(n int) {
+	if p == nil || p.report == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	if time.Since(p.last) >= progressInterval {
+		p.sendLocked()
+	}
+}
+
+// finish sends the final count whatever the interval says.
+func (p *progressReporter) finish() {
+	if p == nil || p.report == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sendLocked()
+}
+
+func (p *progressReporter) send() {
+	if p.report == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sendLocked()
+}
+
+func (p *progressReporter) sendLocked() {
+	p.last = time.Now()
+	p.report(ScanProgress{Stage: p.stage, Done: p.done, Total: p.total})
+}
+
+// runPool calls work for every index below n on at most scanWorkers
+// goroutines. Once ctx is cancelled no new work starts and ctx's error is
+// returned, work already running finishes.
+func runPool(ctx context.Context, n int, work func(i int), progress *progressReporter) error {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(scanWorkers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+				progress.add(1)
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	progress.finish()
+	return err
+}
+
+// walkModFiles finds the mod files under dir, reading directories in
+// parallel. The first error stops the walk, like filepath.Walk would.
+func walkModFiles(ctx context.Context, dir string, progress ProgressFunc) ([]ModInfo, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reporter := newProgressReporter(progress, "Looking for mods", 0)
+
+	var (
+		mu       sync.Mutex
+		mods     []ModInfo
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	// a goroutine per directory, the semaphore is what bounds the reading
+	sem := make(chan struct{}, scanWorkers)
+	var readDir func(path string)
+	readDir = func(path string) {
+		defer wg.Done()
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		entries, err := os.ReadDir(path)
+		var found []ModInfo
+		var subdirs []string
+		for _, entry := range entries {
+			full := filepath.Join(path, entry.Name())
+			if entry.IsDir() {
+				subdirs = append(subdirs, full)
+				continue
+			}
+			if !isModFile(full) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				// removed since the directory was read
+				continue
+			}
+			found = append(found, ModInfo{
+				Name:        strings.TrimSuffix(entry.Name(), disabledSuffix),
+				InstallDate: info.ModTime(),
+				FilePath:    full,
+				FileSize:    info.Size(),
+				Disabled:    strings.HasSuffix(full, disabledSuffix),
+				Inode:       fileInode(info),
+			})
+		}
+		<-sem
+
+		if err != nil {
+			fail(err)
+			return
+		}
+		mu.Lock()
+		mods = append(mods, found...)
+		mu.Unlock()
+		reporter.add(len(found))
+
+		for _, subdir := range subdirs {
+			wg.Add(1)
+			go readDir(subdir)
+		}
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	wg.Add(1)
+	readDir(dir)
+	wg.Wait()
+	reporter.finish()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return mods, nil
+}
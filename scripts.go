@@ -0,0 +1,286 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// the game embeds Python 3.7, bytecode for anything else fails to import
+const gamePythonVersion = "3.7"
+
+var gamePycMagic = pycMagic(3394)
+
+// magic numbers of the versions people usually compile with by accident
+var pycVersions = map[uint16]string{
+	3379: "3.6",
+	3394: "3.7",
+	3413: "3.8",
+	3425: "3.9",
+	3439: "3.10",
+	3495: "3.11",
+	3531: "3.12",
+	3571: "3.13",
+}
+
+func pycMagic(n uint16) []byte {
+	magic := make([]byte, 4)
+	binary.LittleEndian.PutUint16(magic, n)
+	magic[2], magic[3] = '\r', '\n'
+	return magic
+}
+
+// ScriptArchive is what we know about a .ts4script without running it. The
+// game imports these as zips, so module names are just the paths inside.
+type ScriptArchive struct {
+	Path    string
+	Modules []string
+	// modules that only ship as .py, script mods are expected to be compiled
+	SourceOnly []string
+	// .pyc files the game can't load, with the reason
+	BadBytecode []string
+}
+
+func moduleName(name string) string {
+	name = filepath.ToSlash(name)
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".pyc"), ".py")
+}
+
+// topLevelModule is the name a mod is imported under, "mymod/utils" -> "mymod".
+func topLevelModule(module string) string {
+	return strings.SplitN(module, "/", 2)[0]
+}
+
+// pycVersion reads the magic number at the start of a .pyc and says which
+// Python wrote it.
+func pycVersion(file *zip.File) (string, bool, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return "", false, err
+	}
+	defer rc.Close()
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(rc, header); err != nil {
+		return "", false, fmt.Errorf("truncated bytecode")
+	}
+	if bytes.Equal(header, gamePycMagic) {
+		return gamePythonVersion, true, nil
+	}
+	if header[2] != '\r' || header[3] != '\n' {
+		return "", false, fmt.Errorf("not Python bytecode")
+	}
+	if version, ok := pycVersions[binary.LittleEndian.Uint16(header)]; ok {
+		return version, false, nil
+	}
+	return "an unknown Python", false, nil
+}
+
+func ReadScriptArchive(path string) (ScriptArchive, error) {
+	archive := ScriptArchive{Path: path}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return archive, err
+	}
+	defer reader.Close()
+
+	seen := make(map[string]bool)
+	sources := make(map[string]bool)
+	compiled := make(map[string]bool)
+	for _, file := range reader.File {
+		ext := filepath.Ext(file.Name)
+		if ext != ".py" && ext != ".pyc" {
+			continue
+		}
+
+		// zipimport never looks in __pycache__, so these might as well not be there
+		if strings.Contains(filepath.ToSlash(file.Name), "__pycache__/") {
+			archive.BadBytecode = append(archive.BadBytecode, file.Name+": inside __pycache__, the game won't find it")
+			continue
+		}
+
+		module := moduleName(file.Name)
+		if !seen[module] {
+			seen[module] = true
+			archive.Modules = append(archive.Modules, module)
+		}
+
+		if ext == ".py" {
+			sources[module] = true
+			continue
+		}
+
+		compiled[module] = true
+		version, ok, err := pycVersion(file)
+		if err != nil {
+			archive.BadBytecode = append(archive.BadBytecode, file.Name+": "+err.Error())
+		} else if !ok {
+			archive.BadBytecode = append(archive.BadBytecode, fmt.Sprintf("%s: compiled for Python %s, the game runs %s", file.Name, version, gamePythonVersion))
+		}
+	}
+
+	for _, module := range archive.Modules {
+		if sources[module] && !compiled[module] {
+			archive.SourceOnly = append(archive.SourceOnly, module)
+		}
+	}
+
+	return archive, nil
+}
+
+// ScanScriptArchives reads every enabled .ts4script under the Mods folder.
+// Archives that aren't valid zips are skipped, the game can't load them either.
+func ScanScriptArchives(modsDir string) ([]ScriptArchive, error) {
+	var archives []ScriptArchive
+
+	err := filepath.Walk(modsDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".ts4script" {
+			return nil
+		}
+
+		archive, err := ReadScriptArchive(path)
+		if err != nil {
+			return nil
+		}
+		archives = append(archives, archive)
+		return nil
+	})
+
+	return archives, err
+}
+
+// ScriptModuleIndex maps a lowercased module path ("mymod/utils/helpers") to the
+// archive that ships it.
+type ScriptModuleIndex map[string]string
+
+func BuildScriptModuleIndex(modsDir string) (ScriptModuleIndex, error) {
+	archives, err := ScanScriptArchives(modsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(ScriptModuleIndex)
+	for _, archive := range archives {
+		for _, module := range archive.Modules {
+			index[strings.ToLower(module)] = archive.Path
+		}
+	}
+	return index, nil
+}
+
+// where the game's own scripts live in tracebacks. EA builds them under
+// T:\InGame\Gameplay\Scripts, unpacked copies sit in Data/Simulation/Gameplay,
+// and sims4 is the game's core package, no mod can ship its own.
+var gameScriptRoots = []string{"/gameplay/scripts/", "/data/simulation/gameplay/", "/sims4/"}
+
+// Lookup finds the archive for a source path from a traceback. Mods are
+// compiled on the author's machine so the path prefix is theirs, we match the
+// longest tail of the path that is a module we know about. Frames from the
+// game's scripts never match, a mod with a top-level utils module isn't to
+// blame for sims4/utils.py.
+func (index ScriptModuleIndex) Lookup(sourcePath string) (string, string, bool) {
+	path := "/" + strings.ToLower(strings.ReplaceAll(sourcePath, `\`, "/"))
+	for _, root := range gameScriptRoots {
+		if strings.Contains(path, root) {
+			return "", "", false
+		}
+	}
+	parts := strings.Split(moduleName(strings.TrimPrefix(path, "/")), "/")
+
+	// tracebacks from inside an archive name it directly: .../Mods/foo/foo.ts4script/foo/bar.py
+	for i, part := range parts {
+		if strings.HasSuffix(part, ".ts4script") && i < len(parts)-1 {
+			module := strings.Join(parts[i+1:], "/")
+			if archive, ok := index[module]; ok {
+				return archive, module, true
+			}
+		}
+	}
+
+	for i := range parts {
+		module := strings.Join(parts[i:], "/")
+		if module == "__init__" {
+			break
+		}
+		if archive, ok := index[module]; ok {
+			return archive, module, true
+		}
+	}
+
+	return "", "", false
+}
+
+// ScriptModuleConflict is a top-level module shipped by more than one script
+// mod. Only one of them gets imported and the other mod breaks quietly.
+type ScriptModuleConflict struct {
+	Module   string
+	Archives []string
+}
+
+func FindScriptModuleConflicts(archives []ScriptArchive) []ScriptModuleConflict {
+	owners := make(map[string][]string)
+	for _, archive := range archives {
+		seen := make(map[string]bool)
+		for _, module := range archive.Modules {
+			top := strings.ToLower(topLevelModule(module))
+			if seen[top] {
+				continue
+			}
+			seen[top] = true
+			owners[top] = append(owners[top], archive.Path)
+		}
+	}
+
+	var conflicts []ScriptModuleConflict
+	for module, paths := range owners {
+		if len(paths) > 1 {
+			conflicts = append(conflicts, ScriptModuleConflict{Module: module, Archives: paths})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Module < conflicts[j].Module })
+	return conflicts
+}
+
+// formatScriptReport lists every archive with its modules and whatever is
+// wrong with it, then the module clashes between archives.
+func formatScriptReport(modsDir string, archives []ScriptArchive, conflicts []ScriptModuleConflict) string {
+	var b strings.Builder
+
+	if len(archives) == 0 {
+		b.WriteString("No script mods installed.\n")
+	}
+	for _, archive := range archives {
+		name := archive.Path
+		if rel, err := filepath.Rel(modsDir, archive.Path); err == nil {
+			name = rel
+		}
+		fmt.Fprintf(&b, "%s (%d modules)\n", name, len(archive.Modules))
+		for _, module := range archive.Modules {
+			fmt.Fprintf(&b, "    %s\n", module)
+		}
+		for _, module := range archive.SourceOnly {
+			fmt.Fprintf(&b, "    ! %s.py ships without bytecode, the game expects compiled .pyc\n", module)
+		}
+		for _, problem := range archive.BadBytecode {
+			fmt.Fprintf(&b, "    ! %s\n", problem)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		b.WriteString("\nModules shipped by more than one script mod:\n")
+		for _, conflict := range conflicts {
+			fmt.Fprintf(&b, "    %s: %s\n", conflict.Module, strings.Join(conflict.Archives, ", "))
+		}
+	}
+	return b.String()
+}
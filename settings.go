@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
@@ -10,7 +12,7 @@ import (
 func setupSettingsTab() fyne.CanvasObject {
 	settings, err := LoadSettings()
 	if err != nil {
-		settings = AppSettings{ModsDirectory: DefaultModsPath}
+		settings = AppSettings{ModsDirectory: DefaultModsPath, GameDirectory: DefaultGamePath}
 	}
 
 	pathEntry := widget.NewEntry()
@@ -28,19 +30,193 @@ func setupSettingsTab() fyne.CanvasObject {
 
 	pathRow := container.NewBorder(nil, nil, nil, browseButton, pathEntry)
 	
+	gameEntry := widget.NewEntry()
+	gameEntry.SetText(settings.GameDirectory)
+	
+	gameBrowseButton := widget.NewButton("Browse", func() {
+		dlg := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			gameEntry.SetText(uri.Path())
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+		dlg.Show()
+	})
+	
+	gameRow := container.NewBorder(nil, nil, nil, gameBrowseButton, gameEntry)
+	
+	packsLabel := widget.NewLabel("")
+	packsLabel.Wrapping = fyne.TextWrapWord
+	showPacks := func() {
+		if len(settings.OwnedPacks) == 0 {
+			packsLabel.SetText("No packs detected (base game only, or not scanned yet)")
+		} else {
+			packsLabel.SetText(formatPackList(settings.OwnedPacks))
+		}
+	}
+	showPacks()
+	
+	detectButton := widget.NewButton("Detect Packs", func() {
+		packs, err := DetectInstalledPacks(gameEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("can't read game directory: %w", err), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		settings.OwnedPacks = packs
+		showPacks()
+	})
+	
+	installNames := func() []string {
+		names := make([]string, len(settings.Installs))
+		for i, install := range settings.Installs {
+			names[i] = install.Name
+		}
+		return names
+	}
+	
+	selectedInstall := -1
+	for i, install := range settings.Installs {
+		if install.ModsDirectory == settings.ModsDirectory && install.GameDirectory == settings.GameDirectory {
+			selectedInstall = i
+		}
+	}
+	
+	installSelect := widget.NewSelect(installNames(), func(name string) {
+		for i, install := range settings.Installs {
+			if install.Name != name {
+				continue
+			}
+			selectedInstall = i
+			// no documents folder means the game never ran in that prefix,
+			// saving would leave settings with no Mods directory at all
+			if install.ModsDirectory != "" {
+				pathEntry.SetText(install.ModsDirectory)
+			} else {
+				dialog.ShowInformation("No Mods Folder", fmt.Sprintf("%s has no Mods folder yet, run the game once or pick the folder by hand.", install.Name), fyne.CurrentApp().Driver().AllWindows()[0])
+			}
+			gameEntry.SetText(install.GameDirectory)
+			if packs, err := DetectInstalledPacks(install.GameDirectory); err == nil {
+				settings.OwnedPacks = packs
+			} else {
+				settings.OwnedPacks = nil
+			}
+			showPacks()
+			return
+		}
+	})
+	installSelect.PlaceHolder = "(custom paths)"
+	if selectedInstall >= 0 {
+		installSelect.Selected = settings.Installs[selectedInstall].Name
+	}
+	
+	discoverButton := widget.NewButton("Discover", func() {
+		showDiscoveredInstalls(func(install GameInstall) {
+			for _, existing := range settings.Installs {
+				if existing.Name == install.Name {
+					installSelect.SetSelected(install.Name)
+					return
+				}
+			}
+			settings.Installs = append(settings.Installs, install)
+			installSelect.Options = installNames()
+			installSelect.SetSelected(install.Name)
+		})
+	})
+	
+	installRow := container.NewBorder(nil, nil, nil, discoverButton, installSelect)
+	
+	cacheLabel := widget.NewLabel("")
+	cacheLabel.Wrapping = fyne.TextWrapWord
+	showCaches := func() {
+		caches, err := ListGameCaches(pathEntry.Text)
+		if err != nil {
+			cacheLabel.SetText("Can't read the documents folder: " + err.Error())
+			return
+		}
+		if len(caches) == 0 {
+			cacheLabel.SetText("No cache files, nothing to clean")
+			return
+		}
+		var total int64
+		text := ""
+		for _, cache := range caches {
+			total += cache.Size
+			text += fmt.Sprintf("%s: %s\n", cache.Name, formatFileSize(cache.Size))
+		}
+		cacheLabel.SetText(text + "Total: " + formatFileSize(total))
+	}
+	showCaches()
+	
+	cleanButton := widget.NewButton("Clean Now", func() {
+		freed, err := CleanGameCaches(pathEntry.Text)
+		showCaches()
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		dialog.ShowInformation("Cache Cleaned", "Freed "+formatFileSize(freed), fyne.CurrentApp().Driver().AllWindows()[0])
+	})
+	
+	autoCleanCheck := widget.NewCheck("Clean automatically after mod changes", func(checked bool) {
+		settings.AutoCleanCache = checked
+	})
+	autoCleanCheck.SetChecked(settings.AutoCleanCache)
+	
+	launchEntry := widget.NewEntry()
+	launchEntry.SetPlaceHolder(defaultLaunchCommand)
+	launchEntry.SetText(settings.LaunchCommand)
+	
+	languageSelect := widget.NewSelect(gameLanguages(), func(language string) {
+		settings.GameLanguage = language
+	})
+	languageSelect.Selected = settings.GameLanguage
+	
 	saveButton := widget.NewButton("Save Settings", func() {
-		settings.ModsDirectory = pathEntry.Text
-		err := SaveSettings(settings)
+		// other tabs save settings too (Browse filters, Options.ini flags, the
+		// game version), start from what's on disk and only change this tab's fields
+		current, err := LoadSettings()
 		if err != nil {
 			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
 			return
 		}
+		modsChanged := current.ModsDirectory != pathEntry.Text
+		if modsChanged && IsGameRunning() {
+			dialog.ShowError(fmt.Errorf("can't switch Mods folders while the game is running"), fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		current.ModsDirectory = pathEntry.Text
+		current.GameDirectory = gameEntry.Text
+		current.LaunchCommand = launchEntry.Text
+		current.OwnedPacks = settings.OwnedPacks
+		current.AutoCleanCache = settings.AutoCleanCache
+		current.GameLanguage = settings.GameLanguage
+		// each install keeps its own Mods directory, so edits go back into it
+		if selectedInstall >= 0 && selectedInstall < len(settings.Installs) {
+			settings.Installs[selectedInstall].ModsDirectory = pathEntry.Text
+			settings.Installs[selectedInstall].GameDirectory = gameEntry.Text
+		}
+		current.Installs = settings.Installs
+		if err := SaveSettings(current); err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		settings = current
+		if modsChanged {
+			onModsChanged()
+			showCaches()
+		}
 		dialog.ShowInformation("Success", "Settings saved successfully", fyne.CurrentApp().Driver().AllWindows()[0])
 	})
 
 	form := &widget.Form{
 		Items: []*widget.FormItem{
+			{Text: "Game Install", Widget: installRow},
 			{Text: "Mods Directory", Widget: pathRow},
+			{Text: "Game Directory", Widget: gameRow},
+			{Text: "Installed Packs", Widget: container.NewBorder(nil, nil, nil, detectButton, packsLabel)},
+			{Text: "Launch Command", Widget: launchEntry},
+			{Text: "Game Language", Widget: languageSelect},
+			{Text: "Game Cache", Widget: container.NewVBox(container.NewBorder(nil, nil, nil, cleanButton, cacheLabel), autoCleanCheck)},
 		},
 		SubmitText: "Save",
 		OnSubmit: func() {
@@ -52,4 +228,53 @@ func setupSettingsTab() fyne.CanvasObject {
 		widget.NewLabel("Settings"),
 		form,
 	)
+}
+
+func showDiscoveredInstalls(onUse func(GameInstall)) {
+	window := fyne.CurrentApp().Driver().AllWindows()[0]
+	
+	installs := DiscoverGameInstalls()
+	if len(installs) == 0 {
+		dialog.ShowInformation("Discover", "Couldn't find any Sims 4 installs in Steam, Lutris, Heroic or Bottles.", window)
+		return
+	}
+	
+	var discoverDialog dialog.Dialog
+	
+	list := widget.NewList(
+		func() int { return len(installs) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(
+				nil, nil, nil, widget.NewButton("Use", func() {}),
+				container.NewVBox(widget.NewLabel("Name"), widget.NewLabel("Game"), widget.NewLabel("Mods")),
+			)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			install := installs[id]
+			row := item.(*fyne.Container)
+			
+			labels := row.Objects[0].(*fyne.Container)
+			labels.Objects[0].(*widget.Label).SetText(install.Name)
+			labels.Objects[1].(*widget.Label).SetText("Game: " + orNotFound(install.GameDirectory))
+			labels.Objects[2].(*widget.Label).SetText("Mods: " + orNotFound(install.ModsDirectory))
+			
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				onUse(install)
+				discoverDialog.Hide()
+			}
+		},
+	)
+	
+	scroll := container.NewVScroll(list)
+	scroll.SetMinSize(fyne.NewSize(700, 350))
+	
+	discoverDialog = dialog.NewCustom("Discovered Installs", "Close", scroll, window)
+	discoverDialog.Show()
+}
+
+func orNotFound(path string) string {
+	if path == "" {
+		return "not found"
+	}
+	return path
 }
\ No newline at end of file
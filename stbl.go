@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// the top byte of a string table's instance is the language
+var stblLocales = map[byte]string{
+	0x00: "English",
+	0x01: "Chinese (Simplified)",
+	0x02: "Chinese (Traditional)",
+	0x03: "Czech",
+	0x04: "Danish",
+	0x05: "Dutch",
+	0x06: "Finnish",
+	0x07: "French",
+	0x08: "German",
+	0x0B: "Italian",
+	0x0C: "Japanese",
+	0x0D: "Korean",
+	0x0E: "Norwegian",
+	0x0F: "Polish",
+	0x11: "Portuguese (Brazil)",
+	0x12: "Russian",
+	0x13: "Spanish",
+	0x15: "Spanish (Mexico)",
+	0x16: "Swedish",
+}
+
+var errNotSTBL = errors.New("not a string table")
+
+func stblLocale(key ResourceKey) byte {
+	return byte(key.Instance >> 56)
+}
+
+func localeName(code byte) string {
+	if name, ok := stblLocales[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("locale %02X", code)
+}
+
+// ParseSTBL decodes a version 5 string table: a small header and then
+// (key hash, flags, length, UTF-8 text) for every string.
+func ParseSTBL(data []byte) (map[uint32]string, error) {
+	if len(data) < 21 || string(data[0:4]) != "STBL" {
+		return nil, errNotSTBL
+	}
+	if version := binary.LittleEndian.Uint16(data[4:]); version != 5 {
+		return nil, fmt.Errorf("unsupported string table version %d", version)
+	}
+
+	count := binary.LittleEndian.Uint64(data[7:])
+	pos := 21 // magic, version, compressed, count, reserved, total length
+
+	strings := make(map[uint32]string)
+	for i := uint64(0); i < count; i++ {
+		if pos+7 > len(data) {
+			return strings, errors.New("string table is truncated")
+		}
+		key := binary.LittleEndian.Uint32(data[pos:])
+		length := int(binary.LittleEndian.Uint16(data[pos+5:]))
+		pos += 7
+		if pos+length > len(data) {
+			return strings, errors.New("string table is truncated")
+		}
+		strings[key] = string(data[pos : pos+length])
+		pos += length
+	}
+	return strings, nil
+}
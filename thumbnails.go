@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// extracted thumbnails are cached here, named after the package path, size
+// and mtime so a changed package gets a new one
+const thumbnailDir = "thumbnails"
+
+// packages without a thumbnail get an empty marker so they're not reread
+const noThumbnailExt = ".none"
+
+// thumbnail resource types, in the order we prefer them
+var thumbnailResources = []uint32{
+	0x3C1AF1F2, // CAS part thumbnail
+	0x5B282D45, // body part thumbnail
+	0x3C2A8647, // build/buy thumbnail
+	0x0580A2B4, // build/buy thumbnail, medium
+	0x0580A2B5, // build/buy thumbnail, large
+	0x0580A2B6,
+	0xCD9DE247, // sim preset thumbnail
+	0x9C925813, // sim thumbnail
+}
+
+func thumbnailKey(mod ModInfo) string {
+	sum := sha1.Sum([]byte(mod.FilePath + "|" + strconv.FormatInt(mod.FileSize, 10) + "|" + strconv.FormatInt(mod.InstallDate.UnixNano(), 10)))
+	return hex.EncodeToString(sum[:])
+}
+
+func imageExtension(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		return ".png"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return ".jpg"
+	}
+	return ""
+}
+
+// ExtractThumbnail returns the first embedded image in a package that
+// actually decodes. Build/buy thumbnails are JPEGs with an extra alpha chunk
+// the standard decoder skips over. No image and no error means the package
+// really has none, a resource that couldn't be read is an error.
+func ExtractThumbnail(path string) ([]byte, error) {
+	pkg, err := ReadDBPF(path)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := make(map[uint32][]DBPFEntry)
+	for _, entry := range pkg.Entries {
+		byType[entry.Key.Type] = append(byType[entry.Key.Type], entry)
+	}
+
+	var readErr error
+	for _, resourceType := range thumbnailResources {
+		for _, entry := range byType[resourceType] {
+			data, err := pkg.ReadResource(entry)
+			if err != nil {
+				if readErr == nil {
+					readErr = err
+				}
+				continue
+			}
+			if imageExtension(data) == "" {
+				continue
+			}
+			if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+				continue
+			}
+			return data, nil
+		}
+	}
+	return nil, readErr
+}
+
+// CachedThumbnail looks for an already extracted thumbnail. The second result
+// says whether the package was looked at at all.
+func CachedThumbnail(mod ModInfo) (string, bool) {
+	key := thumbnailKey(mod)
+	for _, ext := range []string{".png", ".jpg"} {
+		path := filepath.Join(thumbnailDir, key+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	if _, err := os.Stat(filepath.Join(thumbnailDir, key+noThumbnailExt)); err == nil {
+		return "", true
+	}
+	return "", false
+}
+
+// CacheThumbnail extracts the thumbnail of a package into the cache and
+// returns its path, "" when there is none.
+func CacheThumbnail(mod ModInfo) (string, error) {
+	if path, ok := CachedThumbnail(mod); ok {
+		return path, nil
+	}
+	if err := ensureDirectoryExists(thumbnailDir); err != nil {
+		return "", err
+	}
+
+	key := thumbnailKey(mod)
+	data, err := ExtractThumbnail(mod.FilePath)
+	if err != nil {
+		// could be anything from a half copied file to too many open files,
+		// it's tried again next time
+		return "", err
+	}
+	if data == nil {
+		os.WriteFile(filepath.Join(thumbnailDir, key+noThumbnailExt), nil, 0644)
+		return "", nil
+	}
+
+	path := filepath.Join(thumbnailDir, key+imageExtension(data))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to cache thumbnail: %w", err)
+	}
+	return path, nil
+}
+
+// cacheThumbnails fills the cache for every package that hasn't been looked
+// at yet and reports whether anything new was found.
+func cacheThumbnails(mods []ModInfo) bool {
+	found := false
+	for _, mod := range mods {
+		if modExtension(mod.FilePath) != ".package" {
+			continue
+		}
+		if _, ok := CachedThumbnail(mod); ok {
+			continue
+		}
+		if path, err := CacheThumbnail(mod); err == nil && path != "" {
+			found = true
+		}
+	}
+	return found
+}
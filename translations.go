@@ -0,0 +1,396 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+const defaultGameLanguage = "English"
+
+// localeCode is the string table locale for a language name from stblLocales.
+func localeCode(language string) (byte, bool) {
+	for code, name := range stblLocales {
+		if strings.EqualFold(name, language) {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// gameLanguages lists the languages the game can be set to, English first.
+func gameLanguages() []string {
+	var codes []int
+	for code := range stblLocales {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	languages := make([]string, len(codes))
+	for i, code := range codes {
+		languages[i] = stblLocales[byte(code)]
+	}
+	return languages
+}
+
+// packageStringKeys reads the keys of every string table in a package, per
+// locale. onlyStrings is true when the package has nothing but string tables,
+// which is what a translation looks like.
+func packageStringKeys(path string) (keys map[byte]map[uint32]bool, onlyStrings bool, err error) {
+	pkg, err := ReadDBPF(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	keys = make(map[byte]map[uint32]bool)
+	onlyStrings = len(pkg.Entries) > 0
+	for _, entry := range pkg.Entries {
+		if entry.Key.Type != resourceStringTable {
+			onlyStrings = false
+			continue
+		}
+		data, err := pkg.ReadResource(entry)
+		if err != nil {
+			continue
+		}
+		strs, err := ParseSTBL(data)
+		if err != nil {
+			continue
+		}
+		locale := stblLocale(entry.Key)
+		if keys[locale] == nil {
+			keys[locale] = make(map[uint32]bool)
+		}
+		for key := range strs {
+			keys[locale][key] = true
+		}
+	}
+	return keys, onlyStrings, nil
+}
+
+// sourceKeys are the strings a mod needs translated: its English ones, or all
+// of them when it doesn't ship English.
+func sourceKeys(keys map[byte]map[uint32]bool) map[uint32]bool {
+	if english, ok := keys[0x00]; ok {
+		return english
+	}
+	all := make(map[uint32]bool)
+	for _, table := range keys {
+		for key := range table {
+			all[key] = true
+		}
+	}
+	return all
+}
+
+// MissingTranslation is a mod with strings the game language doesn't have.
+type MissingTranslation struct {
+	Path    string
+	Strings int
+	Missing int
+	// the translation packages already installed for it
+	Translations []string
+}
+
+// FindMissingTranslations reports the packages that show untranslated text in
+// language. Translation packages linked in the manifest count towards their
+// parent, translations nobody is linked to still help whatever they match.
+func FindMissingTranslations(modsDir, language string) ([]MissingTranslation, error) {
+	locale, ok := localeCode(language)
+	if !ok {
+		return nil, fmt.Errorf("unknown game language %q", language)
+	}
+
+	mods, err := scanMods(modsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	byParent, err := TranslationsByParent()
+	if err != nil {
+		return nil, err
+	}
+
+	keysByPath := make(map[string]map[byte]map[uint32]bool)
+	var parents, translations []string
+	for _, mod := range mods {
+		if mod.Disabled || modExtension(mod.FilePath) != ".package" {
+			continue
+		}
+		keys, onlyStrings, err := packageStringKeys(mod.FilePath)
+		if err != nil || len(keys) == 0 {
+			continue
+		}
+		keysByPath[mod.FilePath] = keys
+		if onlyStrings {
+			translations = append(translations, mod.FilePath)
+		} else {
+			parents = append(parents, mod.FilePath)
+		}
+	}
+
+	// translations nobody is linked to help whatever has their keys
+	linked := make(map[string]bool)
+	for _, files := range byParent {
+		for _, file := range files {
+			linked[file] = true
+		}
+	}
+	loose := make(map[uint32]bool)
+	for _, path := range translations {
+		if linked[path] {
+			continue
+		}
+		for key := range keysByPath[path][locale] {
+			loose[key] = true
+		}
+	}
+
+	var missing []MissingTranslation
+	for _, path := range parents {
+		keys := keysByPath[path]
+		own := byParent[filepath.Clean(path)]
+
+		have := keys[locale]
+		count := 0
+	next:
+		for key := range sourceKeys(keys) {
+			if have[key] || loose[key] {
+				continue
+			}
+			for _, translation := range own {
+				if keysByPath[translation][locale][key] {
+					continue next
+				}
+			}
+			count++
+		}
+		if count > 0 {
+			missing = append(missing, MissingTranslation{Path: path, Strings: len(sourceKeys(keys)), Missing: count, Translations: own})
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Missing > missing[j].Missing })
+	return missing, nil
+}
+
+func formatMissingTranslations(modsDir, language string, missing []MissingTranslation) string {
+	if len(missing) == 0 {
+		return "Every installed mod has its text in " + language + ".\n"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d mods show untranslated text in %s:\n", len(missing), language)
+	for _, m := range missing {
+		rel, err := filepath.Rel(modsDir, m.Path)
+		if err != nil {
+			rel = m.Path
+		}
+		if m.Missing == m.Strings {
+			fmt.Fprintf(&b, "  %s: no %s strings (%d)", rel, language, m.Strings)
+		} else {
+			fmt.Fprintf(&b, "  %s: %d of %d strings missing", rel, m.Missing, m.Strings)
+		}
+		if len(m.Translations) > 0 {
+			fmt.Fprintf(&b, ", translation installed is incomplete (%s)", filepath.Base(m.Translations[0]))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+var errNotTranslation = errors.New("that package isn't a translation, it has more than string tables in it")
+
+// FindTranslationParent works out which installed mod a translation package
+// belongs to: the one sharing the most string keys with it.
+func FindTranslationParent(translation, modsDir string) (string, error) {
+	keys, onlyStrings, err := packageStringKeys(translation)
+	if err != nil {
+		return "", err
+	}
+	if !onlyStrings {
+		return "", errNotTranslation
+	}
+	translated := sourceKeys(keys)
+
+	mods, err := scanMods(modsDir)
+	if err != nil {
+		return "", err
+	}
+
+	best, bestCount := "", 0
+	for _, mod := range mods {
+		if modExtension(mod.FilePath) != ".package" || mod.FilePath == translation {
+			continue
+		}
+		modKeys, onlyStrings, err := packageStringKeys(mod.FilePath)
+		if err != nil || onlyStrings {
+			continue
+		}
+		count := 0
+		for key := range sourceKeys(modKeys) {
+			if translated[key] {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = mod.FilePath, count
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("none of the installed mods has the strings %s translates", filepath.Base(translation))
+	}
+	return best, nil
+}
+
+// InstallTranslation installs a copy of a translation package next to its
+// parent mod and links the two in the manifest. A disabled parent gets a
+// disabled translation.
+func InstallTranslation(source, modsDir, parent string) (string, QuarantineEntry, error) {
+	disabled := strings.HasSuffix(parent, disabledSuffix)
+	parent = filepath.Clean(strings.TrimSuffix(parent, disabledSuffix))
+	target := filepath.Join(filepath.Dir(parent), filepath.Base(source))
+	if _, err := os.Stat(target); err == nil {
+		return "", QuarantineEntry{}, fmt.Errorf("%s is already installed", filepath.Base(target))
+	}
+
+	staged, err := stagingPath(target)
+	if err != nil {
+		return "", QuarantineEntry{}, err
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return "", QuarantineEntry{}, err
+	}
+	if err := os.WriteFile(staged, data, 0644); err != nil {
+		return "", QuarantineEntry{}, err
+	}
+
+	outcome, entry, err := admitModFile(staged, modsDir, target, 0)
+	if err != nil || outcome == AdmitQuarantined {
+		return outcome, entry, err
+	}
+
+	manifestEntry := ManifestEntry{Path: target, FileName: filepath.Base(target), Parent: parent}
+	if byPath, err := ManifestByPath(); err == nil {
+		if parentEntry, ok := byPath[parent]; ok {
+			manifestEntry.ModName = parentEntry.ModName
+		} else if parentEntry, ok := byPath[parent+disabledSuffix]; ok {
+			manifestEntry.ModName = parentEntry.ModName
+		}
+	}
+	if err := recordInstall(manifestEntry); err != nil {
+		fmt.Printf("Failed to update manifest: %v\n", err)
+	}
+
+	if disabled && outcome == AdmitInstalled {
+		if _, err := setModEnabled(target, false); err != nil {
+			fmt.Printf("Failed to disable translation %s: %v\n", target, err)
+		}
+	}
+	return outcome, entry, nil
+}
+
+func showTranslations(list *widget.List) {
+	settings, err := LoadSettings()
+	if err != nil {
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		return
+	}
+
+	translationsWindow := fyne.CurrentApp().NewWindow("Translations")
+	translationsWindow.Resize(fyne.NewSize(800, 500))
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	reportLabel := widget.NewLabel("")
+	reportLabel.Wrapping = fyne.TextWrapWord
+
+	scan := func() {
+		statusLabel.SetText("Reading string tables in " + settings.GameLanguage + "...")
+		go func() {
+			missing, err := FindMissingTranslations(settings.ModsDirectory, settings.GameLanguage)
+			fyne.Do(func() {
+				if err != nil {
+					statusLabel.SetText("Scan failed: " + err.Error())
+					return
+				}
+				statusLabel.SetText("Game language: " + settings.GameLanguage + " (change it in Settings)")
+				reportLabel.SetText(formatMissingTranslations(settings.ModsDirectory, settings.GameLanguage, missing))
+			})
+		}()
+	}
+
+	install := func(path, parent string) {
+		outcome, entry, err := InstallTranslation(path, settings.ModsDirectory, parent)
+		if err != nil {
+			dialog.ShowError(err, translationsWindow)
+			return
+		}
+		switch outcome {
+		case AdmitQuarantined:
+			showQuarantinedNotice(entry)
+			return
+		case AdmitQueued:
+			dialog.ShowInformation("Game Running", "The translation will be installed after the game exits.", translationsWindow)
+			return
+		}
+		onModsChanged()
+		refreshModsList(list)
+		scan()
+	}
+
+	importButton := widget.NewButton("Import Translation...", func() {
+		fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			path := reader.URI().Path()
+			reader.Close()
+
+			statusLabel.SetText("Looking for the mod " + filepath.Base(path) + " translates...")
+			go func() {
+				parent, err := FindTranslationParent(path, settings.ModsDirectory)
+				fyne.Do(func() {
+					if err != nil {
+						statusLabel.SetText("")
+						dialog.ShowError(err, translationsWindow)
+						return
+					}
+					rel, relErr := filepath.Rel(settings.ModsDirectory, parent)
+					if relErr != nil {
+						rel = parent
+					}
+					message := fmt.Sprintf("%s translates %s. Install it next to it? It gets disabled and removed together with the mod.", filepath.Base(path), rel)
+					dialog.NewConfirm("Import Translation", message, func(confirmed bool) {
+						if confirmed {
+							install(path, parent)
+						} else {
+							scan()
+						}
+					}, translationsWindow).Show()
+				})
+			}()
+		}, translationsWindow)
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".package"}))
+		fileDialog.Show()
+	})
+
+	translationsWindow.SetContent(container.NewBorder(
+		statusLabel,
+		container.NewHBox(widget.NewButton("Scan Again", scan), importButton),
+		nil, nil,
+		container.NewVScroll(reportLabel),
+	))
+	translationsWindow.Show()
+
+	scan()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trashDir is the home trash from the freedesktop.org trash spec, so removed
+// mods show up in the file manager's trash like anything else.
+func trashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+func trashInfoPath(trashedPath string) string {
+	return filepath.Join(filepath.Dir(filepath.Dir(trashedPath)), "info", filepath.Base(trashedPath)+".trashinfo")
+}
+
+// TrashFile moves path into the trash and returns where it ended up. The
+// .trashinfo is created first with O_EXCL, that's how the spec reserves a
+// name. The spec wants a per-mount trash for other filesystems, we copy into
+// the home trash instead.
+func TrashFile(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	trash, err := trashDir()
+	if err != nil {
+		return "", err
+	}
+	if err := ensureDirectoryExists(filepath.Join(trash, "files")); err != nil {
+		return "", err
+	}
+	if err := ensureDirectoryExists(filepath.Join(trash, "info")); err != nil {
+		return "", err
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: absPath}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+
+	base := filepath.Base(absPath)
+	ext := filepath.Ext(base)
+	for i := 0; i < 1000; i++ {
+		name := base
+		if i > 0 {
+			name = strings.TrimSuffix(base, ext) + "." + strconv.Itoa(i) + ext
+		}
+
+		trashed := filepath.Join(trash, "files", name)
+		infoFile, err := os.OpenFile(trashInfoPath(trashed), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Lstat(trashed); err == nil {
+			// a file left in files/ without its .trashinfo still takes the
+			// name, moving over it would destroy it
+			infoFile.Close()
+			os.Remove(trashInfoPath(trashed))
+			continue
+		}
+		_, err = infoFile.WriteString(info)
+		infoFile.Close()
+		if err != nil {
+			os.Remove(trashInfoPath(trashed))
+			return "", err
+		}
+
+		if err := moveFile(absPath, trashed); err != nil {
+			os.Remove(trashInfoPath(trashed))
+			return "", err
+		}
+		return trashed, nil
+	}
+
+	return "", fmt.Errorf("too many files called %s in the trash", base)
+}
+
+// RestoreFromTrash puts a trashed file back at target.
+func RestoreFromTrash(trashedPath, target string) error {
+	if _, err := os.Stat(trashedPath); err != nil {
+		return fmt.Errorf("%s is no longer in the trash", filepath.Base(target))
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("can't restore %s, something is in the way", target)
+	}
+
+	if err := moveFile(trashedPath, target); err != nil {
+		return err
+	}
+	os.Remove(trashInfoPath(trashedPath))
+	return nil
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// XML tuning is plain text inside the package. Creators also put it under
+// types nobody has a name for, so anything small enough gets sniffed.
+const tuningSniffMaxSize = 512 << 10
+
+var otherResourceNames = map[uint32]string{
+	resourceCASPart:       "CAS part",
+	resourceSkinTone:      "skin tone",
+	resourceClip:          "animation clip",
+	resourceCatalogObject: "catalog object",
+	resourceStringTable:   "string table",
+	0xC0DB5AE7:            "object definition",
+	0x00B2D882:            "texture",
+	0x015A1849:            "geometry",
+	0x01661233:            "model",
+	0x01D10F34:            "model LOD",
+}
+
+func isThumbnailType(t uint32) bool {
+	for _, thumbnail := range thumbnailResources {
+		if t == thumbnail {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceTypeName(t uint32) string {
+	if name, ok := tuningResources[t]; ok {
+		return name
+	}
+	if name, ok := otherResourceNames[t]; ok {
+		return name
+	}
+	if isThumbnailType(t) {
+		return "thumbnail"
+	}
+	return fmt.Sprintf("type %08X", t)
+}
+
+// TuningResource is one XML tuning or snippet file from a package.
+type TuningResource struct {
+	Key ResourceKey
+	// the n and c attributes on the root element, e.g. trait_Happy and Trait
+	Name  string
+	Class string
+	XML   string
+}
+
+func (t TuningResource) Title() string {
+	name := t.Name
+	if name == "" {
+		name = "(unnamed)"
+	}
+	kind := resourceTypeName(t.Key.Type)
+	if t.Class != "" {
+		kind = t.Class
+	}
+	return fmt.Sprintf("%s  [%s, instance %016X]", name, kind, t.Key.Instance)
+}
+
+var (
+	tuningRootPattern = regexp.MustCompile(`<[IM]\s[^>]*>`)
+	tuningAttrPattern = regexp.MustCompile(`\b([nc])="([^"]*)"`)
+)
+
+// isTuningXML tells XML tuning apart from the binary resources.
+func isTuningXML(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return bytes.HasPrefix(data, []byte("<?xml")) || bytes.HasPrefix(data, []byte("<I ")) || bytes.HasPrefix(data, []byte("<M "))
+}
+
+func parseTuning(key ResourceKey, data []byte) TuningResource {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	tuning := TuningResource{Key: key, XML: strings.ReplaceAll(string(data), "\r\n", "\n")}
+	if root := tuningRootPattern.FindString(tuning.XML); root != "" {
+		for _, attr := range tuningAttrPattern.FindAllStringSubmatch(root, -1) {
+			if attr[1] == "n" {
+				tuning.Name = attr[2]
+			} else {
+				tuning.Class = attr[2]
+			}
+		}
+	}
+	return tuning
+}
+
+// mayBeTuning is true for the resources worth reading to find the XML.
+func mayBeTuning(entry DBPFEntry) bool {
+	if _, ok := tuningResources[entry.Key.Type]; ok {
+		return true
+	}
+	if _, ok := otherResourceNames[entry.Key.Type]; ok || isThumbnailType(entry.Key.Type) {
+		return false
+	}
+	return entry.MemSize <= tuningSniffMaxSize
+}
+
+// ExtractTuning returns the XML tuning and snippets in a package, by name.
+func ExtractTuning(path string) ([]TuningResource, error) {
+	pkg, err := ReadDBPF(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tunings []TuningResource
+	for _, entry := range pkg.Entries {
+		if !mayBeTuning(entry) {
+			continue
+		}
+		data, err := pkg.ReadResource(entry)
+		if err != nil || !isTuningXML(data) {
+			continue
+		}
+		tunings = append(tunings, parseTuning(entry.Key, data))
+	}
+
+	sort.Slice(tunings, func(i, j int) bool {
+		if tunings[i].Name != tunings[j].Name {
+			return tunings[i].Name < tunings[j].Name
+		}
+		return tunings[i].Key.Instance < tunings[j].Key.Instance
+	})
+	return tunings, nil
+}
+
+const (
+	ResourceAdded   = "added"
+	ResourceRemoved = "removed"
+	ResourceChanged = "changed"
+)
+
+// ResourceChange is one difference between two versions of a package. Tuning
+// comes with a line diff of the XML, other resources only with their sizes.
+type ResourceChange struct {
+	Key     ResourceKey
+	Status  string
+	Name    string
+	OldSize uint32
+	NewSize uint32
+	Diff    []string
+}
+
+func (c ResourceChange) Title() string {
+	name := c.Name
+	if name == "" {
+		name = resourceTypeName(c.Key.Type)
+	}
+	return fmt.Sprintf("%s %s  [%016X]", c.Status, name, c.Key.Instance)
+}
+
+// DiffPackages compares an installed package with a new version of it,
+// resource by resource.
+func DiffPackages(oldPath, newPath string) ([]ResourceChange, error) {
+	oldPkg, err := ReadDBPF(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newPkg, err := ReadDBPF(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	oldEntries := make(map[ResourceKey]DBPFEntry)
+	for _, entry := range oldPkg.Entries {
+		oldEntries[entry.Key] = entry
+	}
+	newEntries := make(map[ResourceKey]DBPFEntry)
+	for _, entry := range newPkg.Entries {
+		newEntries[entry.Key] = entry
+	}
+
+	// names for the tuning that was added or removed come from the XML too
+	describe := func(pkg *DBPFPackage, entry DBPFEntry) string {
+		if !mayBeTuning(entry) {
+			return ""
+		}
+		data, err := pkg.ReadResource(entry)
+		if err != nil || !isTuningXML(data) {
+			return ""
+		}
+		return parseTuning(entry.Key, data).Name
+	}
+
+	var changes []ResourceChange
+	for key, oldEntry := range oldEntries {
+		newEntry, ok := newEntries[key]
+		if !ok {
+			changes = append(changes, ResourceChange{Key: key, Status: ResourceRemoved, Name: describe(oldPkg, oldEntry), OldSize: oldEntry.MemSize})
+			continue
+		}
+
+		oldData, oldErr := oldPkg.ReadResource(oldEntry)
+		newData, newErr := newPkg.ReadResource(newEntry)
+		if oldErr != nil || newErr != nil || bytes.Equal(oldData, newData) {
+			continue
+		}
+
+		change := ResourceChange{Key: key, Status: ResourceChanged, OldSize: oldEntry.MemSize, NewSize: newEntry.MemSize}
+		if isTuningXML(oldData) && isTuningXML(newData) {
+			oldTuning, newTuning := parseTuning(key, oldData), parseTuning(key, newData)
+			change.Name = newTuning.Name
+			change.Diff = diffLines(strings.Split(oldTuning.XML, "\n"), strings.Split(newTuning.XML, "\n"))
+		}
+		changes = append(changes, change)
+	}
+	for key, newEntry := range newEntries {
+		if _, ok := oldEntries[key]; !ok {
+			changes = append(changes, ResourceChange{Key: key, Status: ResourceAdded, Name: describe(newPkg, newEntry), NewSize: newEntry.MemSize})
+		}
+	}
+
+	// tuning first, it's what changes the game
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if (a.Name != "") != (b.Name != "") {
+			return a.Name != ""
+		}
+		if a.Status != b.Status {
+			return a.Status < b.Status
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Key.String() < b.Key.String()
+	})
+	return changes, nil
+}
+
+// when the changed middle would need a bigger table than this the diff is
+// just all old lines then all new ones
+const diffMaxCells = 4 << 20
+
+const diffContext = 3
+
+// diffLines is a line diff in the usual "-"/"+"/" " form, with a few lines of
+// context around every change and @@ markers with the line numbers.
+func diffLines(a, b []string) []string {
+	// shared start and end are the common case for tuning, only diff the middle
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var ops []string
+	for _, line := range a[:prefix] {
+		ops = append(ops, " "+line)
+	}
+	if len(midA)*len(midB) > diffMaxCells {
+		for _, line := range midA {
+			ops = append(ops, "-"+line)
+		}
+		for _, line := range midB {
+			ops = append(ops, "+"+line)
+		}
+	} else {
+		ops = append(ops, lcsDiff(midA, midB)...)
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, " "+line)
+	}
+
+	// keep only the context around the changes
+	keep := make([]bool, len(ops))
+	for i, op := range ops {
+		if op[0] == ' ' {
+			continue
+		}
+		for j := i - diffContext; j <= i+diffContext; j++ {
+			if j >= 0 && j < len(ops) {
+				keep[j] = true
+			}
+		}
+	}
+
+	var out []string
+	lineA, lineB := 1, 1
+	inHunk := false
+	for i, op := range ops {
+		if keep[i] {
+			if !inHunk {
+				out = append(out, fmt.Sprintf("@@ -%d +%d @@", lineA, lineB))
+				inHunk = true
+			}
+			out = append(out, op)
+		} else {
+			inHunk = false
+		}
+		if op[0] != '+' {
+			lineA++
+		}
+		if op[0] != '-' {
+			lineB++
+		}
+	}
+	return out
+}
+
+func lcsDiff(a, b []string) []string {
+	// lengths[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var ops []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, " "+a[i])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			ops = append(ops, "-"+a[i])
+			i++
+		default:
+			ops = append(ops, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, "+"+b[j])
+	}
+	return ops
+}
+
+// formatPackageDiff is the plain text changelog for the command line.
+func formatPackageDiff(changes []ResourceChange) string {
+	if len(changes) == 0 {
+		return "No differences.\n"
+	}
+
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Status]++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d added, %d removed, %d changed\n", counts[ResourceAdded], counts[ResourceRemoved], counts[ResourceChanged])
+	for _, change := range changes {
+		b.WriteString("\n" + change.Title())
+		if change.Status == ResourceChanged && change.Diff == nil {
+			fmt.Fprintf(&b, " (%s -> %s)", formatFileSize(int64(change.OldSize)), formatFileSize(int64(change.NewSize)))
+		}
+		b.WriteString("\n")
+		for _, line := range change.Diff {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// showTuningViewer lists the tuning in a package with the XML of the selected
+// one next to it.
+func showTuningViewer(path string) {
+	tuningWindow := fyne.CurrentApp().NewWindow("Tuning - " + filepath.Base(path))
+	tuningWindow.Resize(fyne.NewSize(1000, 600))
+
+	statusLabel := widget.NewLabel("Reading " + filepath.Base(path) + "...")
+	xmlLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+
+	var tunings, shown []TuningResource
+	tuningList := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject { return widget.NewLabel("Tuning") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(shown[id].Title())
+		},
+	)
+	tuningList.OnSelected = func(id widget.ListItemID) {
+		xmlLabel.SetText(shown[id].XML)
+	}
+
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("Filter by name, class or instance")
+	filterEntry.OnChanged = func(text string) {
+		query := strings.ToLower(strings.TrimSpace(text))
+		shown = nil
+		for _, tuning := range tunings {
+			if query == "" || strings.Contains(strings.ToLower(tuning.Title()), query) {
+				shown = append(shown, tuning)
+			}
+		}
+		tuningList.UnselectAll()
+		tuningList.Refresh()
+	}
+
+	split := container.NewHSplit(
+		container.NewBorder(filterEntry, nil, nil, nil, tuningList),
+		container.NewScroll(xmlLabel),
+	)
+	split.Offset = 0.4
+	tuningWindow.SetContent(container.NewBorder(statusLabel, nil, nil, nil, split))
+	tuningWindow.Show()
+
+	go func() {
+		found, err := ExtractTuning(path)
+		fyne.Do(func() {
+			if err != nil {
+				statusLabel.SetText("Can't read the package: " + err.Error())
+				return
+			}
+			if len(found) == 0 {
+				statusLabel.SetText("No XML tuning in this package.")
+				return
+			}
+			statusLabel.SetText(fmt.Sprintf("%d tuning resources", len(found)))
+			tunings, shown = found, found
+			tuningList.Refresh()
+		})
+	}()
+}
+
+// showUpdateChanges downloads a new file of an installed mod and shows what
+// it changes, as the changelog the author didn't write.
+func showUpdateChanges(mod Mod, file File, installedPath string) {
+	changesWindow := fyne.CurrentApp().NewWindow("Changes - " + mod.Name)
+	changesWindow.Resize(fyne.NewSize(1000, 600))
+
+	statusLabel := widget.NewLabel("Downloading " + file.FileName + "...")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	diffLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+
+	var changes []ResourceChange
+	changesList := widget.NewList(
+		func() int { return len(changes) },
+		func() fyne.CanvasObject { return widget.NewLabel("Change") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(changes[id].Title())
+		},
+	)
+	changesList.OnSelected = func(id widget.ListItemID) {
+		change := changes[id]
+		switch {
+		case change.Diff != nil:
+			diffLabel.SetText(strings.Join(change.Diff, "\n"))
+		case change.Status == ResourceChanged:
+			diffLabel.SetText(fmt.Sprintf("Binary resource changed, %s -> %s", formatFileSize(int64(change.OldSize)), formatFileSize(int64(change.NewSize))))
+		default:
+			diffLabel.SetText(fmt.Sprintf("%s: %s %s", change.Key, resourceTypeName(change.Key.Type), change.Status))
+		}
+	}
+
+	split := container.NewHSplit(changesList, container.NewScroll(diffLabel))
+	split.Offset = 0.4
+	changesWindow.SetContent(container.NewBorder(statusLabel, nil, nil, nil, split))
+	changesWindow.Show()
+
+	go func() {
+		newPath, err := downloadToTemp(mod, file)
+		if err != nil {
+			fyne.Do(func() { statusLabel.SetText("Download failed: " + err.Error()) })
+			return
+		}
+		defer os.Remove(newPath)
+
+		found, err := DiffPackages(installedPath, newPath)
+		fyne.Do(func() {
+			if err != nil {
+				statusLabel.SetText("Can't compare: " + err.Error())
+				return
+			}
+			counts := make(map[string]int)
+			for _, change := range found {
+				counts[change.Status]++
+			}
+			statusLabel.SetText(fmt.Sprintf("%s compared to the installed %s: %d added, %d removed, %d changed",
+				file.FileName, filepath.Base(installedPath), counts[ResourceAdded], counts[ResourceRemoved], counts[ResourceChanged]))
+			changes = found
+			changesList.Refresh()
+		})
+	}()
+}
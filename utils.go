@@ -3,6 +3,7 @@ package main
 import (
 	"compress/gzip"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -71,7 +72,21 @@ func ensureModsDirectory(path string) error {
 	if _, err := os.Stat(readmePath); os.IsNotExist(err) {
 		content := "This directory is managed by Sims 4 Mod Manager.\n" +
 			"Please do not modify the files in this directory manually.\n"
-		return os.WriteFile(readmePath, []byte(content), 0644)
+		if err := os.WriteFile(readmePath, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	
+	if _, err := os.Stat(resourceCfgPath(path)); os.IsNotExist(err) {
+		return WriteDefaultResourceCfg(path)
+	}
+	
+	cfg, err := ParseResourceCfg(path)
+	if err != nil {
+		return fmt.Errorf("can't read Resource.cfg: %w", err)
+	}
+	for _, problem := range cfg.Problems {
+		fmt.Printf("Resource.cfg: %s\n", problem)
 	}
 	
 	return nil
@@ -87,4 +102,17 @@ func loadRecentMods() ([]ModInfo, error) {
 	
 	err := loadCompressedJson(&mods, filename)
 	return mods, err
+}
+
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
 }
\ No newline at end of file